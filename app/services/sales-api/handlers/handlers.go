@@ -1,25 +1,99 @@
 package handlers
 
 import (
-	"github.com/maxkulish/service-api/app/services/sales-api/handlers/v1/testgrp"
 	"net/http"
 	"os"
+	"time"
 
-	"github.com/dimfeld/httptreemux/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/maxkulish/service-api/app/services/sales-api/handlers/v1/checkgrp"
+	"github.com/maxkulish/service-api/app/services/sales-api/handlers/v1/levelgrp"
+	"github.com/maxkulish/service-api/app/services/sales-api/handlers/v1/testgrp"
+	"github.com/maxkulish/service-api/app/services/sales-api/handlers/v1/usergrp"
+	"github.com/maxkulish/service-api/business/core/user"
+	"github.com/maxkulish/service-api/business/core/user/stores/userdb"
+	"github.com/maxkulish/service-api/business/web/auth"
+	"github.com/maxkulish/service-api/business/web/v1/debug"
+	"github.com/maxkulish/service-api/business/web/v1/mid"
+	"github.com/maxkulish/service-api/foundation/web"
 	"go.uber.org/zap"
 )
 
+// DebugMuxConfig contains all the systems required by the debug handlers.
+type DebugMuxConfig struct {
+	Build  string
+	Log    *zap.SugaredLogger
+	Level  *levelgrp.Handlers
+	Checks map[string]checkgrp.Checker
+
+	// RequireClientCert limits every route but the probes to clients that
+	// presented a certificate.
+	RequireClientCert bool
+}
+
+// DebugMux registers all the debug standard library routes and then custom
+// debug application routes for the service. This bypasses the use of the
+// DefaultServerMux. Using the DefaultServerMux would be a security risk since
+// a dependency could inject a handler into our service without us knowing it.
+func DebugMux(cfg DebugMuxConfig) http.Handler {
+	mux := debug.StandardLibraryMux()
+	mux.HandleFunc("/debug/loglevel", cfg.Level.Level)
+
+	var private http.Handler = mux
+	if cfg.RequireClientCert {
+		private = debug.RequireClientCert(mux)
+	}
+
+	// The probes stay open since the kubelet can't present a certificate.
+	probes := http.NewServeMux()
+	probes.Handle("/", private)
+
+	cgh := checkgrp.New(cfg.Build, cfg.Log, cfg.Checks)
+	probes.HandleFunc("/debug/readiness", cgh.Readiness)
+	probes.HandleFunc("/debug/liveness", cgh.Liveness)
+
+	return probes
+}
+
 // APIMuxConfig contains all the mandatory systems required by handlers.
 type APIMuxConfig struct {
 	Shutdown chan os.Signal
 	Log      *zap.SugaredLogger
+	DB       *sqlx.DB
+	Auth     *auth.Auth
+	TokenTTL time.Duration
 }
 
 // APIMux constructs an http.Handler with all application routes defined.
-func APIMux(cfg APIMuxConfig) http.Handler {
-	mux := httptreemux.NewContextMux()
+func APIMux(cfg APIMuxConfig) *web.App {
+	return apiMux(cfg, user.NewCore(userdb.NewStore(cfg.Log, cfg.DB)))
+}
+
+// apiMux defines the routes on top of the given user core, so the tests can
+// use one that doesn't need a database.
+func apiMux(cfg APIMuxConfig, usrCore *user.Core) *web.App {
+	app := web.NewApp(cfg.Shutdown, mid.Logger(cfg.Log), mid.Errors(cfg.Log), mid.Metrics(), mid.Panics())
+
+	app.Handle(http.MethodGet, "", "/test", testgrp.Test)
+
+	// Register user management and authentication endpoints.
+	ugh := usergrp.New(usrCore, cfg.Auth, cfg.TokenTTL)
+
+	// Only the token endpoint is open, it is how a client logs in. A user
+	// can read and update their own record, anything else needs an admin.
+	// The token of a user that was disabled stops working right away.
+	authen := mid.Authenticate(cfg.Auth)
+	enabled := mid.Enabled(usrCore)
+	admin := mid.Authorize(cfg.Auth, auth.RoleAdmin)
+	adminOrSelf := mid.AuthorizeSelf(cfg.Auth, "user_id", auth.RoleAdmin)
 
-	mux.Handle(http.MethodGet, "/test", testgrp.Test)
+	v1 := app.Group("v1")
+	v1.Handle(http.MethodGet, "/users/token", ugh.Token)
+	v1.Handle(http.MethodGet, "/users", ugh.Query, authen, enabled, admin)
+	v1.Handle(http.MethodGet, "/users/:user_id", ugh.QueryByID, authen, enabled, adminOrSelf)
+	v1.Handle(http.MethodPost, "/users", ugh.Create, authen, enabled, admin)
+	v1.Handle(http.MethodPut, "/users/:user_id", ugh.Update, authen, enabled, adminOrSelf)
+	v1.Handle(http.MethodDelete, "/users/:user_id", ugh.Delete, authen, enabled, admin)
 
-	return mux
+	return app
 }
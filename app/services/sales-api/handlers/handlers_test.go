@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/maxkulish/service-api/business/core/user"
+	"github.com/maxkulish/service-api/business/core/user/stores/usermem"
+	"github.com/maxkulish/service-api/business/web/auth"
+	"github.com/maxkulish/service-api/foundation/keystore"
+	"go.uber.org/zap"
+)
+
+const kid = "s4sKIjD9kIRjxs2tulPqGLdxSfgPErRN1Mu3Hd9k9NQ"
+
+// userTests holds a mux backed by an in memory store with an admin and a
+// user, and the tokens they got from the token endpoint.
+type userTests struct {
+	t          *testing.T
+	app        http.Handler
+	core       *user.Core
+	auth       *auth.Auth
+	admin      user.User
+	user       user.User
+	adminToken string
+	userToken  string
+}
+
+func newUserTests(t *testing.T) *userTests {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := auth.New(auth.Config{
+		KeyLookup: keystore.NewMap(map[string]*rsa.PrivateKey{kid: key}),
+		ActiveKID: kid,
+		Issuer:    "service project",
+	})
+	if err != nil {
+		t.Fatalf("constructing auth: %s", err)
+	}
+
+	core := user.NewCore(usermem.NewStore())
+	ctx := context.Background()
+
+	admin, err := core.Create(ctx, user.NewUser{
+		Name:     "Admin Gopher",
+		Email:    "admin@example.com",
+		Roles:    []string{auth.RoleAdmin, auth.RoleUser},
+		Password: "gophers",
+	})
+	if err != nil {
+		t.Fatalf("creating admin: %s", err)
+	}
+
+	usr, err := core.Create(ctx, user.NewUser{
+		Name:     "User Gopher",
+		Email:    "user@example.com",
+		Roles:    []string{auth.RoleUser},
+		Password: "gophers",
+	})
+	if err != nil {
+		t.Fatalf("creating user: %s", err)
+	}
+
+	ut := userTests{
+		t:     t,
+		core:  core,
+		auth:  a,
+		admin: admin,
+		user:  usr,
+		app: apiMux(APIMuxConfig{
+			Shutdown: make(chan os.Signal, 1),
+			Log:      zap.NewNop().Sugar(),
+			Auth:     a,
+			TokenTTL: time.Hour,
+		}, core),
+	}
+	ut.adminToken = ut.token("admin@example.com", "gophers")
+	ut.userToken = ut.token("user@example.com", "gophers")
+
+	return &ut
+}
+
+// do sends a request with the token and returns the status and the body.
+func (ut *userTests) do(method string, path string, token string, body string) (int, string) {
+	ut.t.Helper()
+
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	ut.app.ServeHTTP(w, r)
+
+	b, err := io.ReadAll(w.Result().Body)
+	if err != nil {
+		ut.t.Fatalf("reading body: %s", err)
+	}
+
+	return w.Code, string(b)
+}
+
+// login calls the token endpoint with the credentials and returns the
+// status and the body.
+func (ut *userTests) login(email string, password string) (int, string) {
+	ut.t.Helper()
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/users/token", nil)
+	r.SetBasicAuth(email, password)
+
+	w := httptest.NewRecorder()
+	ut.app.ServeHTTP(w, r)
+
+	return w.Code, w.Body.String()
+}
+
+// token logs in through the token endpoint.
+func (ut *userTests) token(email string, password string) string {
+	ut.t.Helper()
+
+	status, body := ut.login(email, password)
+	if status != http.StatusOK {
+		ut.t.Fatalf("token for %s: got status %d: %s", email, status, body)
+	}
+
+	var tkn struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal([]byte(body), &tkn); err != nil {
+		ut.t.Fatalf("decoding token: %s", err)
+	}
+
+	return tkn.Token
+}
+
+func TestUsersAuthentication(t *testing.T) {
+	ut := newUserTests(t)
+
+	// A token that is valid in every way but the key it was signed with.
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "service project",
+			Subject:   ut.admin.ID.String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Roles: []string{auth.RoleAdmin},
+	})
+	forged.Header["kid"] = kid
+	forgedToken, err := forged.SignedString(otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "missing", token: ""},
+		{name: "malformed", token: "not-a-token"},
+		{name: "forged", token: forgedToken},
+		{name: "truncated", token: ut.adminToken[:len(ut.adminToken)-4]},
+	}
+
+	routes := []struct{ method, path string }{
+		{http.MethodGet, "/v1/users"},
+		{http.MethodGet, "/v1/users/" + ut.admin.ID.String()},
+		{http.MethodPost, "/v1/users"},
+		{http.MethodPut, "/v1/users/" + ut.admin.ID.String()},
+		{http.MethodDelete, "/v1/users/" + ut.admin.ID.String()},
+	}
+
+	for _, tt := range tests {
+		for _, rt := range routes {
+			if status, body := ut.do(rt.method, rt.path, tt.token, "{}"); status != http.StatusUnauthorized {
+				t.Errorf("%s: %s %s: got status %d, want %d: %s", tt.name, rt.method, rt.path, status, http.StatusUnauthorized, body)
+			}
+		}
+	}
+}
+
+func TestUsersAuthorization(t *testing.T) {
+	ut := newUserTests(t)
+
+	self := "/v1/users/" + ut.user.ID.String()
+	other := "/v1/users/" + ut.admin.ID.String()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		status int
+	}{
+		{name: "user lists users", method: http.MethodGet, path: "/v1/users", token: ut.userToken, status: http.StatusForbidden},
+		{name: "user creates user", method: http.MethodPost, path: "/v1/users", token: ut.userToken, body: `{"name":"Jill","email":"jill@example.com","roles":["USER"],"password":"gophers","passwordConfirm":"gophers"}`, status: http.StatusForbidden},
+		{name: "user deletes user", method: http.MethodDelete, path: other, token: ut.userToken, status: http.StatusForbidden},
+		{name: "user deletes self", method: http.MethodDelete, path: self, token: ut.userToken, status: http.StatusForbidden},
+		{name: "user reads self", method: http.MethodGet, path: self, token: ut.userToken, status: http.StatusOK},
+		{name: "user reads other", method: http.MethodGet, path: other, token: ut.userToken, status: http.StatusForbidden},
+		{name: "user updates self", method: http.MethodPut, path: self, token: ut.userToken, body: `{"name":"Gopher"}`, status: http.StatusOK},
+		{name: "user updates other", method: http.MethodPut, path: other, token: ut.userToken, body: `{"name":"Gopher"}`, status: http.StatusForbidden},
+		{name: "user grants self roles", method: http.MethodPut, path: self, token: ut.userToken, body: `{"roles":["ADMIN"]}`, status: http.StatusForbidden},
+		{name: "user enables self", method: http.MethodPut, path: self, token: ut.userToken, body: `{"enabled":true}`, status: http.StatusForbidden},
+		{name: "admin lists users", method: http.MethodGet, path: "/v1/users", token: ut.adminToken, status: http.StatusOK},
+		{name: "admin reads other", method: http.MethodGet, path: self, token: ut.adminToken, status: http.StatusOK},
+		{name: "admin updates other roles", method: http.MethodPut, path: self, token: ut.adminToken, body: `{"roles":["USER"]}`, status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, body := ut.do(tt.method, tt.path, tt.token, tt.body); status != tt.status {
+				t.Fatalf("got status %d, want %d: %s", status, tt.status, body)
+			}
+		})
+	}
+
+	usr, err := ut.core.QueryByID(context.Background(), ut.user.ID)
+	if err != nil {
+		t.Fatalf("query user: %s", err)
+	}
+	if len(usr.Roles) != 1 || usr.Roles[0] != auth.RoleUser {
+		t.Fatalf("user ended up with roles %v", usr.Roles)
+	}
+}
+
+func TestUsersDisabled(t *testing.T) {
+	ut := newUserTests(t)
+
+	path := "/v1/users/" + ut.user.ID.String()
+	if status, body := ut.do(http.MethodPut, path, ut.adminToken, `{"enabled":false}`); status != http.StatusOK {
+		t.Fatalf("disabling user: got status %d: %s", status, body)
+	}
+
+	// The token the user got before is refused from now on.
+	if status, body := ut.do(http.MethodGet, path, ut.userToken, ""); status != http.StatusUnauthorized {
+		t.Fatalf("got status %d, want %d: %s", status, http.StatusUnauthorized, body)
+	}
+}
+
+func TestUsersCreate(t *testing.T) {
+	ut := newUserTests(t)
+
+	newUser := func(email string, password string) string {
+		b, err := json.Marshal(map[string]any{
+			"name":            "Jill Gopher",
+			"email":           email,
+			"roles":           []string{auth.RoleUser},
+			"password":        password,
+			"passwordConfirm": password,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{name: "new", body: newUser("Jill@Example.com", "gophers"), status: http.StatusCreated},
+		{name: "duplicate", body: newUser("user@example.com", "gophers"), status: http.StatusConflict},
+		{name: "duplicate in other case", body: newUser("JILL@example.com", "gophers"), status: http.StatusConflict},
+		{name: "password of 72 bytes", body: newUser("max@example.com", strings.Repeat("x", 72)), status: http.StatusCreated},
+		{name: "password over 72 bytes", body: newUser("long@example.com", strings.Repeat("x", 73)), status: http.StatusBadRequest},
+		{name: "multibyte password over 72 bytes", body: newUser("utf8@example.com", strings.Repeat("é", 37)), status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, body := ut.do(http.MethodPost, "/v1/users", ut.adminToken, tt.body); status != tt.status {
+				t.Fatalf("got status %d, want %d: %s", status, tt.status, body)
+			}
+		})
+	}
+
+	// The new user logs in whatever the case of the email.
+	ut.token("JILL@EXAMPLE.COM", "gophers")
+
+	t.Run("update to duplicate", func(t *testing.T) {
+		path := "/v1/users/" + ut.user.ID.String()
+		if status, body := ut.do(http.MethodPut, path, ut.userToken, `{"email":"Admin@Example.com"}`); status != http.StatusConflict {
+			t.Fatalf("got status %d, want %d: %s", status, http.StatusConflict, body)
+		}
+	})
+
+	t.Run("update password over 72 bytes", func(t *testing.T) {
+		path := "/v1/users/" + ut.user.ID.String()
+		password := strings.Repeat("x", 73)
+		body := `{"password":"` + password + `","passwordConfirm":"` + password + `"}`
+		if status, body := ut.do(http.MethodPut, path, ut.userToken, body); status != http.StatusBadRequest {
+			t.Fatalf("got status %d, want %d: %s", status, http.StatusBadRequest, body)
+		}
+	})
+}
+
+func TestUsersToken(t *testing.T) {
+	ut := newUserTests(t)
+
+	// Disabled users can't log in.
+	disabled := false
+	if _, err := ut.core.Update(context.Background(), ut.user, user.UpdateUser{Enabled: &disabled}); err != nil {
+		t.Fatalf("disabling user: %s", err)
+	}
+
+	t.Run("no credentials", func(t *testing.T) {
+		if status, body := ut.do(http.MethodGet, "/v1/users/token", "", ""); status != http.StatusUnauthorized {
+			t.Fatalf("got status %d, want %d: %s", status, http.StatusUnauthorized, body)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+	}{
+		{name: "wrong password", email: "admin@example.com", password: "gopher"},
+		{name: "empty password", email: "admin@example.com"},
+		{name: "unknown email", email: "nobody@example.com", password: "gophers"},
+		{name: "disabled user", email: "user@example.com", password: "gophers"},
+	}
+
+	// Every failure answers the same, so the answer doesn't tell which
+	// emails belong to a user.
+	_, want := ut.login("admin@example.com", "gopher")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := ut.login(tt.email, tt.password)
+			if status != http.StatusUnauthorized {
+				t.Fatalf("got status %d, want %d: %s", status, http.StatusUnauthorized, body)
+			}
+			if body != want {
+				t.Fatalf("got body %s, want %s", body, want)
+			}
+		})
+	}
+}
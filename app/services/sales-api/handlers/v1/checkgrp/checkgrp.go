@@ -0,0 +1,154 @@
+// Package checkgrp maintains the group of handlers for health checking.
+package checkgrp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// readinessTimeout is the time all registered checks have to complete.
+const readinessTimeout = time.Second
+
+// Checker is implemented by the dependencies a service needs to be able to
+// handle requests, such as a database.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc allows the use of an ordinary function as a Checker.
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f(ctx).
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// =============================================================================
+
+// Handlers manages the set of check endpoints.
+type Handlers struct {
+	build  string
+	log    *zap.SugaredLogger
+	checks map[string]Checker
+}
+
+// New constructs a Handlers api for the check group. The checks are run by
+// the readiness endpoint and keyed by the name reported on failure.
+func New(build string, log *zap.SugaredLogger, checks map[string]Checker) *Handlers {
+	return &Handlers{
+		build:  build,
+		log:    log,
+		checks: checks,
+	}
+}
+
+// Readiness checks if the registered dependencies are ready and if not will
+// return a 503 status. Do not respond by just returning an error because
+// further up in the call stack it will interpret that as a non-trusted error.
+func (h *Handlers) Readiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	failed := make(map[string]string)
+
+	var wg sync.WaitGroup
+	wg.Add(len(h.checks))
+
+	for name, checker := range h.checks {
+		go func(name string, checker Checker) {
+			defer wg.Done()
+
+			if err := checker.Check(ctx); err != nil {
+				h.log.Errorw("readiness failure", "check", name, "ERROR", err)
+
+				mu.Lock()
+				failed[name] = err.Error()
+				mu.Unlock()
+			}
+		}(name, checker)
+	}
+
+	wg.Wait()
+
+	data := struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks,omitempty"`
+	}{
+		Status: "ok",
+	}
+
+	statusCode := http.StatusOK
+	if len(failed) > 0 {
+		data.Status = "not ready"
+		data.Checks = failed
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	if err := response(w, statusCode, data); err != nil {
+		h.log.Errorw("readiness", "ERROR", err)
+	}
+
+	h.log.Infow("readiness", "statusCode", statusCode, "method", r.Method, "path", r.URL.Path, "remoteaddr", r.RemoteAddr)
+}
+
+// Liveness returns simple status info if the service is alive. If the
+// app is deployed to a Kubernetes cluster, it will also return pod, node, and
+// namespace details via the Downward API. The Kubernetes environment variables
+// need to be set within your Pod/Deployment manifest.
+func (h *Handlers) Liveness(w http.ResponseWriter, r *http.Request) {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unavailable"
+	}
+
+	data := struct {
+		Status     string `json:"status,omitempty"`
+		Build      string `json:"build,omitempty"`
+		Host       string `json:"host,omitempty"`
+		Name       string `json:"name,omitempty"`
+		PodIP      string `json:"podIP,omitempty"`
+		Node       string `json:"node,omitempty"`
+		Namespace  string `json:"namespace,omitempty"`
+		GOMAXPROCS string `json:"GOMAXPROCS,omitempty"`
+	}{
+		Status:     "up",
+		Build:      h.build,
+		Host:       host,
+		Name:       os.Getenv("KUBERNETES_NAME"),
+		PodIP:      os.Getenv("KUBERNETES_POD_IP"),
+		Node:       os.Getenv("KUBERNETES_NODE_NAME"),
+		Namespace:  os.Getenv("KUBERNETES_NAMESPACE"),
+		GOMAXPROCS: os.Getenv("GOMAXPROCS"),
+	}
+
+	statusCode := http.StatusOK
+	if err := response(w, statusCode, data); err != nil {
+		h.log.Errorw("liveness", "ERROR", err)
+	}
+
+	// Liveness is called frequently so only log it at debug level.
+	h.log.Debugw("liveness", "statusCode", statusCode, "method", r.Method, "path", r.URL.Path, "remoteaddr", r.RemoteAddr)
+}
+
+func response(w http.ResponseWriter, statusCode int, data any) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if _, err := w.Write(jsonData); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,232 @@
+// Package levelgrp maintains the group of handlers for changing the log level
+// of a running service.
+package levelgrp
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// The current level and when a temporary change expires are published so
+// on-call engineers can see when verbose logging is on.
+var (
+	expLevel   = new(expvar.String)
+	expExpires = new(expvar.String)
+)
+
+func init() {
+	m := expvar.NewMap("loglevel")
+	m.Set("level", expLevel)
+	m.Set("expires", expExpires)
+}
+
+// Handlers manages the set of log level endpoints.
+type Handlers struct {
+	log   *zap.SugaredLogger
+	level zap.AtomicLevel
+
+	mu      sync.Mutex
+	base    zapcore.Level
+	expires time.Time
+	revert  *time.Timer
+	gen     uint64
+}
+
+// New constructs a Handlers api for the level group.
+func New(log *zap.SugaredLogger, level zap.AtomicLevel) *Handlers {
+	h := Handlers{
+		log:   log,
+		level: level,
+	}
+	h.publish()
+
+	return &h
+}
+
+// Level reports the current log level on GET and changes it on PUT. A change
+// with a duration reverts to the previous level once the duration passes.
+//
+//	curl -X PUT localhost:4000/debug/loglevel -d '{"level":"debug","duration":"15m"}'
+func (h *Handlers) Level(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.current(w)
+
+	case http.MethodPut:
+		h.change(w, r)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		response(w, http.StatusMethodNotAllowed, errorResponse{Error: http.StatusText(http.StatusMethodNotAllowed)})
+	}
+}
+
+func (h *Handlers) current(w http.ResponseWriter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := response(w, http.StatusOK, h.status()); err != nil {
+		h.log.Errorw("loglevel", "ERROR", err)
+	}
+}
+
+func (h *Handlers) change(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Level    zapcore.Level `json:"level"`
+		Duration string        `json:"duration"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response(w, http.StatusBadRequest, errorResponse{Error: "unable to decode payload: " + err.Error()})
+		return
+	}
+
+	var d time.Duration
+	if req.Duration != "" {
+		var err error
+		if d, err = time.ParseDuration(req.Duration); err != nil || d <= 0 {
+			if err == nil {
+				err = errors.New("must be positive")
+			}
+			response(w, http.StatusBadRequest, errorResponse{Error: "invalid duration: " + err.Error()})
+			return
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	from := h.level.Level()
+
+	// A temporary change reverts to the level that was set before any
+	// temporary change was made, not to the previous temporary level.
+	if h.revert != nil {
+		h.revert.Stop()
+		h.revert = nil
+	} else {
+		h.base = from
+	}
+
+	// Every change starts a new generation so a timer that already fired
+	// for an older change can tell it is stale.
+	h.gen++
+
+	h.expires = time.Time{}
+	if d > 0 {
+		h.expires = time.Now().Add(d).UTC()
+
+		gen := h.gen
+		h.revert = time.AfterFunc(d, func() { h.expire(gen) })
+	}
+
+	h.level.SetLevel(req.Level)
+	h.publish()
+
+	h.log.Warnw("log level changed", "from", from, "to", req.Level, "expires", h.expires, "remoteaddr", r.RemoteAddr)
+
+	if err := response(w, http.StatusOK, h.status()); err != nil {
+		h.log.Errorw("loglevel", "ERROR", err)
+	}
+}
+
+// Set changes the level the service runs at, as when the configuration is
+// reloaded. A temporary change made through the endpoint stays in place
+// until it expires and then reverts to this level.
+func (h *Handlers) Set(level zapcore.Level) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	from := h.base
+	h.base = level
+
+	if h.revert != nil {
+		h.log.Infow("log level changed", "from", from, "to", level, "pending", h.level.Level(), "expires", h.expires)
+		return
+	}
+
+	from = h.level.Level()
+	h.level.SetLevel(level)
+	h.publish()
+
+	h.log.Infow("log level changed", "from", from, "to", level)
+}
+
+// expire restores the level that was in place before a temporary change. A
+// timer that fired while a newer change was being made is ignored.
+func (h *Handlers) expire(gen uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.gen != gen {
+		return
+	}
+
+	from := h.level.Level()
+
+	h.revert = nil
+	h.expires = time.Time{}
+	h.level.SetLevel(h.base)
+	h.publish()
+
+	h.log.Warnw("log level reverted", "from", from, "to", h.base)
+}
+
+// status must be called while holding the mutex.
+func (h *Handlers) status() levelStatus {
+	ls := levelStatus{
+		Level: h.level.Level().String(),
+	}
+
+	if !h.expires.IsZero() {
+		ls.Expires = &h.expires
+		ls.Revert = h.base.String()
+	}
+
+	return ls
+}
+
+// publish must be called while holding the mutex.
+func (h *Handlers) publish() {
+	expLevel.Set(h.level.Level().String())
+
+	var expires string
+	if !h.expires.IsZero() {
+		expires = h.expires.Format(time.RFC3339)
+	}
+	expExpires.Set(expires)
+}
+
+// =============================================================================
+
+type levelStatus struct {
+	Level   string     `json:"level"`
+	Expires *time.Time `json:"expires,omitempty"`
+	Revert  string     `json:"revert,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func response(w http.ResponseWriter, statusCode int, data any) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if _, err := w.Write(jsonData); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package levelgrp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxkulish/service-api/app/services/sales-api/handlers/v1/levelgrp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func put(t *testing.T, h *levelgrp.Handlers, body string) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	h.Level(w, httptest.NewRequest(http.MethodPut, "/debug/loglevel", strings.NewReader(body)))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT %s: got %d: %s", body, w.Code, w.Body)
+	}
+}
+
+func TestLevelExpires(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	h := levelgrp.New(zap.NewNop().Sugar(), level)
+
+	// A duration this short fires while the handler is still returning.
+	for i := 0; i < 100; i++ {
+		put(t, h, `{"level":"debug","duration":"1ns"}`)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for level.Level() != zapcore.InfoLevel {
+		if time.Now().After(deadline) {
+			t.Fatalf("level not reverted: %s", level.Level())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// A newer change without a duration is not undone by an older timer.
+	put(t, h, `{"level":"debug","duration":"10ms"}`)
+	put(t, h, `{"level":"warn"}`)
+
+	time.Sleep(50 * time.Millisecond)
+
+	if got := level.Level(); got != zapcore.WarnLevel {
+		t.Fatalf("stale timer changed the level to %s", got)
+	}
+}
+
+func TestSetDuringTemporaryChange(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	h := levelgrp.New(zap.NewNop().Sugar(), level)
+
+	put(t, h, `{"level":"debug","duration":"50ms"}`)
+
+	// The configured level changes while the temporary one is in place.
+	h.Set(zapcore.ErrorLevel)
+
+	if got := level.Level(); got != zapcore.DebugLevel {
+		t.Fatalf("temporary level replaced: got %s", got)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for level.Level() != zapcore.ErrorLevel {
+		if time.Now().After(deadline) {
+			t.Fatalf("reverted to %s, want the configured level", level.Level())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	h.Set(zapcore.WarnLevel)
+
+	if got := level.Level(); got != zapcore.WarnLevel {
+		t.Fatalf("configured level not applied: got %s", got)
+	}
+}
@@ -1,17 +1,20 @@
+// Package testgrp contains a simple handler used to exercise the web framework.
 package testgrp
 
 import (
-	"encoding/json"
+	"context"
 	"net/http"
+
+	"github.com/maxkulish/service-api/foundation/web"
 )
 
 // Test is a basic HTTP Handler.
-func Test(w http.ResponseWriter, r *http.Request) {
+func Test(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	status := struct {
 		Status string
 	}{
 		Status: "OK",
 	}
 
-	json.NewEncoder(w).Encode(status)
+	return web.Respond(ctx, w, status, http.StatusOK)
 }
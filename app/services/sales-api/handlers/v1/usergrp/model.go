@@ -0,0 +1,179 @@
+package usergrp
+
+import (
+	"errors"
+	"time"
+
+	"github.com/maxkulish/service-api/business/core/user"
+	"github.com/maxkulish/service-api/business/web/auth"
+	v1 "github.com/maxkulish/service-api/business/web/v1"
+)
+
+// AppUser represents information about an individual user.
+type AppUser struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Email       string   `json:"email"`
+	Roles       []string `json:"roles"`
+	Department  string   `json:"department"`
+	Enabled     bool     `json:"enabled"`
+	DateCreated string   `json:"dateCreated"`
+	DateUpdated string   `json:"dateUpdated"`
+}
+
+func toAppUser(usr user.User) AppUser {
+	return AppUser{
+		ID:          usr.ID.String(),
+		Name:        usr.Name,
+		Email:       usr.Email,
+		Roles:       usr.Roles,
+		Department:  usr.Department,
+		Enabled:     usr.Enabled,
+		DateCreated: usr.DateCreated.Format(time.RFC3339),
+		DateUpdated: usr.DateUpdated.Format(time.RFC3339),
+	}
+}
+
+func toAppUsers(usrs []user.User) []AppUser {
+	items := make([]AppUser, len(usrs))
+	for i, usr := range usrs {
+		items[i] = toAppUser(usr)
+	}
+	return items
+}
+
+// =============================================================================
+
+// maxPasswordBytes is the longest password bcrypt hashes in full. It ignores
+// anything past it, so a longer password is refused rather than shortened.
+const maxPasswordBytes = 72
+
+// AppNewUser contains information needed to create a new user.
+type AppNewUser struct {
+	Name            string   `json:"name"`
+	Email           string   `json:"email"`
+	Roles           []string `json:"roles"`
+	Department      string   `json:"department"`
+	Password        string   `json:"password"`
+	PasswordConfirm string   `json:"passwordConfirm"`
+}
+
+func toCoreNewUser(app AppNewUser) user.NewUser {
+	return user.NewUser{
+		Name:       app.Name,
+		Email:      app.Email,
+		Roles:      app.Roles,
+		Department: app.Department,
+		Password:   app.Password,
+	}
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppNewUser) Validate() error {
+	var fe v1.FieldErrors
+
+	if app.Name == "" {
+		fe = append(fe, v1.FieldError{Field: "name", Err: "is required"})
+	}
+	if err := user.ValidateEmail(app.Email); err != nil {
+		fe = append(fe, v1.FieldError{Field: "email", Err: err.Error()})
+	}
+	if err := validateRoles(app.Roles); err != nil {
+		fe = append(fe, v1.FieldError{Field: "roles", Err: err.Error()})
+	}
+
+	// The confirmation is only compared once the password itself is valid,
+	// so a password gets a single error.
+	switch {
+	case app.Password == "":
+		fe = append(fe, v1.FieldError{Field: "password", Err: "is required"})
+	case len(app.Password) > maxPasswordBytes:
+		fe = append(fe, v1.FieldError{Field: "password", Err: "must be at most 72 bytes"})
+	case app.PasswordConfirm != app.Password:
+		fe = append(fe, v1.FieldError{Field: "passwordConfirm", Err: "does not match password"})
+	}
+
+	if fe != nil {
+		return fe
+	}
+	return nil
+}
+
+// =============================================================================
+
+// AppUpdateUser contains information needed to update a user. Fields that
+// are left out keep their value.
+type AppUpdateUser struct {
+	Name            *string  `json:"name"`
+	Email           *string  `json:"email"`
+	Roles           []string `json:"roles"`
+	Department      *string  `json:"department"`
+	Password        *string  `json:"password"`
+	PasswordConfirm *string  `json:"passwordConfirm"`
+	Enabled         *bool    `json:"enabled"`
+}
+
+func toCoreUpdateUser(app AppUpdateUser) user.UpdateUser {
+	return user.UpdateUser{
+		Name:       app.Name,
+		Email:      app.Email,
+		Roles:      app.Roles,
+		Department: app.Department,
+		Password:   app.Password,
+		Enabled:    app.Enabled,
+	}
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppUpdateUser) Validate() error {
+	var fe v1.FieldErrors
+
+	if app.Name != nil && *app.Name == "" {
+		fe = append(fe, v1.FieldError{Field: "name", Err: "can't be empty"})
+	}
+	if app.Email != nil {
+		if err := user.ValidateEmail(*app.Email); err != nil {
+			fe = append(fe, v1.FieldError{Field: "email", Err: err.Error()})
+		}
+	}
+	if app.Roles != nil {
+		if err := validateRoles(app.Roles); err != nil {
+			fe = append(fe, v1.FieldError{Field: "roles", Err: err.Error()})
+		}
+	}
+	if app.Password != nil {
+		switch {
+		case *app.Password == "":
+			fe = append(fe, v1.FieldError{Field: "password", Err: "can't be empty"})
+		case len(*app.Password) > maxPasswordBytes:
+			fe = append(fe, v1.FieldError{Field: "password", Err: "must be at most 72 bytes"})
+		case app.PasswordConfirm == nil || *app.PasswordConfirm != *app.Password:
+			fe = append(fe, v1.FieldError{Field: "passwordConfirm", Err: "does not match password"})
+		}
+	}
+
+	if fe != nil {
+		return fe
+	}
+	return nil
+}
+
+// =============================================================================
+
+// Token is the response of a successful login.
+type Token struct {
+	Token string `json:"token"`
+}
+
+func validateRoles(roles []string) error {
+	if len(roles) == 0 {
+		return errors.New("at least one role is required")
+	}
+
+	for _, role := range roles {
+		if role != auth.RoleAdmin && role != auth.RoleUser {
+			return errors.New("must be ADMIN or USER")
+		}
+	}
+	return nil
+}
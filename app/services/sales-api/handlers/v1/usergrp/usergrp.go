@@ -0,0 +1,179 @@
+// Package usergrp maintains the group of handlers for user access.
+package usergrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/maxkulish/service-api/business/core/user"
+	"github.com/maxkulish/service-api/business/web/auth"
+	v1 "github.com/maxkulish/service-api/business/web/v1"
+	"github.com/maxkulish/service-api/foundation/web"
+)
+
+// Handlers manages the set of user endpoints.
+type Handlers struct {
+	user     *user.Core
+	auth     *auth.Auth
+	tokenTTL time.Duration
+}
+
+// New constructs a handlers for route access. The tokens handed out by the
+// token endpoint expire after tokenTTL.
+func New(user *user.Core, auth *auth.Auth, tokenTTL time.Duration) *Handlers {
+	return &Handlers{
+		user:     user,
+		auth:     auth,
+		tokenTTL: tokenTTL,
+	}
+}
+
+// Create adds a new user to the system.
+func (h *Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppNewUser
+	if err := web.Decode(r, &app); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	usr, err := h.user.Create(ctx, toCoreNewUser(app))
+	if err != nil {
+		if errors.Is(err, user.ErrUniqueEmail) {
+			return v1.NewRequestError(user.ErrUniqueEmail, http.StatusConflict)
+		}
+		return fmt.Errorf("create: email[%s]: %w", app.Email, err)
+	}
+
+	return web.Respond(ctx, w, toAppUser(usr), http.StatusCreated)
+}
+
+// Update updates a user in the system.
+func (h *Handlers) Update(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppUpdateUser
+	if err := web.Decode(r, &app); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	// A user updating their own record can't grant themselves roles or
+	// enable a disabled account.
+	if app.Roles != nil || app.Enabled != nil {
+		if err := h.auth.Authorize(auth.GetClaims(ctx), auth.RoleAdmin); err != nil {
+			return v1.NewRequestError(err, http.StatusForbidden)
+		}
+	}
+
+	usr, err := h.queryByID(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	updUsr, err := h.user.Update(ctx, usr, toCoreUpdateUser(app))
+	if err != nil {
+		if errors.Is(err, user.ErrUniqueEmail) {
+			return v1.NewRequestError(user.ErrUniqueEmail, http.StatusConflict)
+		}
+		return fmt.Errorf("update: userID[%s]: %w", usr.ID, err)
+	}
+
+	return web.Respond(ctx, w, toAppUser(updUsr), http.StatusOK)
+}
+
+// Delete removes a user from the system.
+func (h *Handlers) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, err := h.queryByID(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	if err := h.user.Delete(ctx, usr); err != nil {
+		return fmt.Errorf("delete: userID[%s]: %w", usr.ID, err)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Query returns a page of users with the total number of users.
+func (h *Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	page, rowsPerPage, err := v1.ParsePage(r)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	usrs, err := h.user.Query(ctx, page, rowsPerPage)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	total, err := h.user.Count(ctx)
+	if err != nil {
+		return fmt.Errorf("count: %w", err)
+	}
+
+	return web.Respond(ctx, w, v1.NewPageDocument(toAppUsers(usrs), total, page, rowsPerPage), http.StatusOK)
+}
+
+// QueryByID returns a user by its ID.
+func (h *Handlers) QueryByID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, err := h.queryByID(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, toAppUser(usr), http.StatusOK)
+}
+
+// Token provides an API token for the user whose email and password are
+// passed with basic authentication.
+func (h *Handlers) Token(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	email, pass, ok := r.BasicAuth()
+	if !ok {
+		return v1.NewRequestError(errors.New("must provide email and password in Basic auth"), http.StatusUnauthorized)
+	}
+
+	usr, err := h.user.Authenticate(ctx, email, pass)
+	if err != nil {
+		if errors.Is(err, user.ErrAuthenticationFailure) {
+			return v1.NewRequestError(err, http.StatusUnauthorized)
+		}
+		return fmt.Errorf("authenticate: %w", err)
+	}
+
+	now := time.Now().UTC()
+	claims := auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   usr.ID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(h.tokenTTL)),
+		},
+		Roles: usr.Roles,
+	}
+
+	token, err := h.auth.GenerateToken(claims)
+	if err != nil {
+		return fmt.Errorf("generatetoken: %w", err)
+	}
+
+	return web.Respond(ctx, w, Token{Token: token}, http.StatusOK)
+}
+
+// queryByID looks up the user named by the user_id parameter of the route.
+func (h *Handlers) queryByID(ctx context.Context, r *http.Request) (user.User, error) {
+	userID, err := uuid.Parse(web.Param(r, "user_id"))
+	if err != nil {
+		return user.User{}, v1.NewRequestError(v1.NewFieldsError("user_id", errors.New("must be a valid id")), http.StatusBadRequest)
+	}
+
+	usr, err := h.user.QueryByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return user.User{}, v1.NewRequestError(user.ErrNotFound, http.StatusNotFound)
+		}
+		return user.User{}, fmt.Errorf("querybyid: userID[%s]: %w", userID, err)
+	}
+
+	return usr, nil
+}
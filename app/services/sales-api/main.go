@@ -2,10 +2,11 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"errors"
 	"expvar"
 	"fmt"
-	"github.com/maxkulish/service-api/app/services/sales-api/handlers"
 	"net/http"
 	"os"
 	"os/signal"
@@ -14,29 +15,45 @@ import (
 	"time"
 
 	"github.com/ardanlabs/conf/v3"
-	"github.com/maxkulish/service-api/business/web/v1/debug"
+	"github.com/google/uuid"
+	"github.com/maxkulish/service-api/app/services/sales-api/handlers"
+	"github.com/maxkulish/service-api/app/services/sales-api/handlers/v1/checkgrp"
+	"github.com/maxkulish/service-api/app/services/sales-api/handlers/v1/levelgrp"
+	"github.com/maxkulish/service-api/business/data/dbmigrate"
+	"github.com/maxkulish/service-api/business/data/sqldb"
+	"github.com/maxkulish/service-api/business/web/auth"
+	"github.com/maxkulish/service-api/foundation/certs"
+	"github.com/maxkulish/service-api/foundation/config"
+	"github.com/maxkulish/service-api/foundation/keystore"
+	"github.com/maxkulish/service-api/foundation/lifecycle"
 	"github.com/maxkulish/service-api/foundation/logger"
+	"github.com/maxkulish/service-api/foundation/upgrade"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 var build = "develop"
 
 func main() {
-	log, err := logger.New("sales-api")
+	level := zap.NewAtomicLevelAt(zap.InfoLevel)
+
+	log, err := logger.New("sales-api", level)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 	defer log.Sync()
 
-	if err := run(log); err != nil {
+	if err := run(log, level); err != nil {
 		log.Errorw("startup", "ERROR", err)
 		log.Sync()
 		os.Exit(1)
 	}
 }
 
-func run(log *zap.SugaredLogger) error {
+func run(log *zap.SugaredLogger, level zap.AtomicLevel) error {
 	// -------------------------------------------------
 	// GOMAXPROCS
 	// The argument 0 in runtime.GOMAXPROCS(0) doesn't change
@@ -48,13 +65,65 @@ func run(log *zap.SugaredLogger) error {
 
 	cfg := struct {
 		conf.Version
+		Config struct {
+			File          string
+			WatchInterval time.Duration `conf:"default:10s"`
+		}
+		Log struct {
+			Level string `conf:"default:info" reload:"true"`
+		}
+		// A reloaded ReadTimeout or WriteTimeout is applied to each request
+		// of the api through http.ResponseController. Both are timed from
+		// when the handler starts, so the time spent reading the headers no
+		// longer counts. The http.Server keeps the timeouts it started with
+		// and a reloaded 0 leaves those in place, so a timeout can't be
+		// turned off without a restart.
 		Web struct {
-			ReadTimeout     time.Duration `conf:"default:5s"`
-			WriteTimeout    time.Duration `conf:"default:10s"`
-			IdleTimeout     time.Duration `conf:"default:120s"`
-			ShutdownTimeout time.Duration `conf:"default:20s,mask"`
-			APIHost         string        `conf:"default:0.0.0.0:3000"`
-			DebugHost       string        `conf:"default:0.0.0.0:4000"`
+			ReadTimeout       time.Duration `conf:"default:5s" reload:"true"`
+			ReadHeaderTimeout time.Duration `conf:"default:2s"`
+			WriteTimeout      time.Duration `conf:"default:10s" reload:"true"`
+			IdleTimeout       time.Duration `conf:"default:120s"`
+			ShutdownTimeout   time.Duration `conf:"default:20s,mask"`
+			UpgradeTimeout    time.Duration `conf:"default:30s"`
+			MaxHeaderBytes    int           `conf:"default:65536"`
+			APIHost           string        `conf:"default:0.0.0.0:3000"`
+			DebugHost         string        `conf:"default:0.0.0.0:4000"`
+		}
+		HTTP2 struct {
+			Cleartext            bool   `conf:"env:HTTP2_CLEARTEXT,flag:http2-cleartext"`
+			MaxConcurrentStreams uint32 `conf:"default:250,env:HTTP2_MAX_CONCURRENT_STREAMS,flag:http2-max-concurrent-streams"`
+			MaxReadFrameSize     uint32 `conf:"default:1048576,env:HTTP2_MAX_READ_FRAME_SIZE,flag:http2-max-read-frame-size"`
+		}
+		DB struct {
+			User           string `conf:"default:postgres"`
+			Password       string `conf:"default:postgres,mask"`
+			Host           string `conf:"default:localhost:5432"`
+			Name           string `conf:"default:postgres"`
+			Schema         string
+			MaxIdleConns   int           `conf:"default:2"`
+			MaxOpenConns   int           `conf:"default:0"`
+			DisableTLS     bool          `conf:"default:true"`
+			MigrateOnStart bool          `conf:"default:false"`
+			MigrateTimeout time.Duration `conf:"default:60s"`
+		}
+		Auth struct {
+			KeysFolder string `conf:"default:zarf/keys/"`
+			ActiveKID  string
+			Issuer     string        `conf:"default:service project"`
+			TokenTTL   time.Duration `conf:"default:1h"`
+
+			// DevEphemeralKey signs the tokens with a key that only lives as
+			// long as the process when no ActiveKID is set. Its tokens stop
+			// working on a restart and aren't accepted by other replicas or
+			// made by sales-admin gentoken, so it is for local development.
+			DevEphemeralKey bool
+		}
+		TLS struct {
+			CertFile               string
+			KeyFile                string
+			ClientCAFile           string
+			APIRequireClientCert   bool
+			DebugRequireClientCert bool
 		}
 	}{
 		Version: conf.Version{
@@ -63,8 +132,14 @@ func run(log *zap.SugaredLogger) error {
 		},
 	}
 
+	// The value before parsing is what a reload parses the configuration into.
+	base := cfg
+
+	// Settings are applied in order of precedence: defaults, the config file,
+	// environment variables and finally command line flags.
 	const prefix = "SALES"
-	help, err := conf.Parse(prefix, &cfg)
+	configFile := config.Path(prefix, os.Args[1:])
+	help, err := config.Parse(prefix, &cfg, config.WithFile(configFile))
 	if err != nil {
 		if errors.Is(err, conf.ErrHelpWanted) {
 			fmt.Println(help)
@@ -73,6 +148,12 @@ func run(log *zap.SugaredLogger) error {
 		return fmt.Errorf("parsing config: %w", err)
 	}
 
+	logLevel, err := zapcore.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		return fmt.Errorf("parsing log level: %w", err)
+	}
+	level.SetLevel(logLevel)
+
 	// -------------------------------------------------------------------------
 	// App Starting
 
@@ -88,18 +169,107 @@ func run(log *zap.SugaredLogger) error {
 	expvar.NewString("build").Set(build)
 
 	// -------------------------------------------------------------------------
-	// Start Debug Service
+	// Authentication Support
 
-	log.Infow("startup", "status", "debug v1 router started", "host", cfg.Web.DebugHost)
+	log.Infow("startup", "status", "initializing authentication support")
 
-	// The debug router is launched in a separate goroutine. If the service is terminated,
-	// any orphaned goroutine will be gracefully shut down by the shutdown handler.
-	// It is safe to close the goroutine as the debug endpoint is read-only and does not maintain state.
-	go func() {
-		if err := http.ListenAndServe(cfg.Web.DebugHost, debug.StandardLibraryMux()); err != nil {
-			log.Errorw("shutdown", "status", "debug v1 router closed", "host", cfg.Web.DebugHost, "ERROR", err)
+	var ks auth.KeyLookup
+	activeKID := cfg.Auth.ActiveKID
+
+	switch {
+	case activeKID == "" && !cfg.Auth.DevEphemeralKey:
+		return errors.New("no active kid configured: set SALES_AUTH_ACTIVE_KID, or SALES_AUTH_DEV_EPHEMERAL_KEY for local development")
+
+	case activeKID == "":
+		log.Warnw("startup", "status", "no active kid configured, using an ephemeral signing key")
+
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return fmt.Errorf("generating ephemeral key: %w", err)
 		}
-	}()
+
+		activeKID = "ephemeral-" + uuid.NewString()
+		ks = keystore.NewMap(map[string]*rsa.PrivateKey{activeKID: privateKey})
+
+	default:
+		fsks, err := keystore.NewFS(os.DirFS(cfg.Auth.KeysFolder))
+		if err != nil {
+			return fmt.Errorf("reading keys: %w", err)
+		}
+		ks = fsks
+	}
+
+	a, err := auth.New(auth.Config{
+		KeyLookup: ks,
+		ActiveKID: activeKID,
+		Issuer:    cfg.Auth.Issuer,
+	})
+	if err != nil {
+		return fmt.Errorf("constructing auth: %w", err)
+	}
+
+	// -------------------------------------------------------------------------
+	// Database Support
+
+	log.Infow("startup", "status", "initializing database support", "host", cfg.DB.Host)
+
+	db, err := sqldb.Open(sqldb.Config{
+		User:         cfg.DB.User,
+		Password:     cfg.DB.Password,
+		Host:         cfg.DB.Host,
+		Name:         cfg.DB.Name,
+		Schema:       cfg.DB.Schema,
+		MaxIdleConns: cfg.DB.MaxIdleConns,
+		MaxOpenConns: cfg.DB.MaxOpenConns,
+		DisableTLS:   cfg.DB.DisableTLS,
+	})
+	if err != nil {
+		return fmt.Errorf("connecting to db: %w", err)
+	}
+
+	// Migrating on start is meant for local development. Elsewhere the
+	// schema is brought up to date by the admin tool before a deploy.
+	if cfg.DB.MigrateOnStart {
+		log.Infow("startup", "status", "migrating database")
+
+		mctx, mcancel := context.WithTimeout(context.Background(), cfg.DB.MigrateTimeout)
+		err := dbmigrate.Migrate(mctx, log, db)
+		mcancel()
+
+		if err != nil {
+			db.Close()
+			return fmt.Errorf("migrating db: %w", err)
+		}
+	}
+
+	// -------------------------------------------------------------------------
+	// Start Debug Service
+
+	log.Infow("startup", "status", "initializing debug v1 router")
+
+	// The level handlers are shared with the config reloader so a change to
+	// the configured level doesn't undo a temporary change in progress.
+	lgh := levelgrp.New(log, level)
+
+	debugMux := handlers.DebugMux(handlers.DebugMuxConfig{
+		Build: build,
+		Log:   log,
+		Level: lgh,
+		Checks: map[string]checkgrp.Checker{
+			"database": checkgrp.CheckerFunc(func(ctx context.Context) error {
+				return sqldb.StatusCheck(ctx, db)
+			}),
+		},
+		RequireClientCert: cfg.TLS.DebugRequireClientCert,
+	})
+
+	debug := http.Server{
+		Addr:              cfg.Web.DebugHost,
+		Handler:           debugMux,
+		ReadHeaderTimeout: cfg.Web.ReadHeaderTimeout,
+		MaxHeaderBytes:    cfg.Web.MaxHeaderBytes,
+		ErrorLog:          zap.NewStdLog(log.Desugar()),
+	}
 
 	// -------------------------------------------------------------------------
 	// Start API Service
@@ -118,42 +288,207 @@ func run(log *zap.SugaredLogger) error {
 	apiMux := handlers.APIMux(handlers.APIMuxConfig{
 		Shutdown: shutdown,
 		Log:      log,
+		DB:       db,
+		Auth:     a,
+		TokenTTL: cfg.Auth.TokenTTL,
 	})
 
+	// The read header timeout and the header size limit stop clients from
+	// holding connections open by sending their headers slowly.
 	api := http.Server{
-		Addr:         cfg.Web.APIHost,
-		Handler:      apiMux,
-		ReadTimeout:  cfg.Web.ReadTimeout,
-		WriteTimeout: cfg.Web.WriteTimeout,
-		IdleTimeout:  cfg.Web.IdleTimeout,
-		ErrorLog:     zap.NewStdLog(log.Desugar()),
+		Addr:              cfg.Web.APIHost,
+		Handler:           apiMux,
+		ReadTimeout:       cfg.Web.ReadTimeout,
+		ReadHeaderTimeout: cfg.Web.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Web.WriteTimeout,
+		IdleTimeout:       cfg.Web.IdleTimeout,
+		MaxHeaderBytes:    cfg.Web.MaxHeaderBytes,
+		ErrorLog:          zap.NewStdLog(log.Desugar()),
 	}
 
-	serverErrors := make(chan error, 1)
-	go func() {
-		log.Infow("startup", "status", "api router started", "host", api.Addr)
-		serverErrors <- api.ListenAndServe()
-	}()
+	// -------------------------------------------------------------------------
+	// TLS Support
+
+	switch {
+	case cfg.TLS.CertFile != "" || cfg.TLS.KeyFile != "":
+		log.Infow("startup", "status", "initializing TLS support", "clientCA", cfg.TLS.ClientCAFile != "")
+
+		// The certificates are read again from disk when they are rotated.
+		cr, err := certs.NewReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("loading certificates: %w", err)
+		}
+
+		if api.TLSConfig, err = cr.ServerConfig(cfg.TLS.APIRequireClientCert); err != nil {
+			return fmt.Errorf("configuring api TLS: %w", err)
+		}
+
+		// The handshake on the debug listener only verifies a certificate if
+		// the client presents one, so the kubelet can still reach the probes.
+		// The debug routes check that a certificate was presented.
+		if cfg.TLS.DebugRequireClientCert && cfg.TLS.ClientCAFile == "" {
+			return errors.New("requiring debug client certificates needs a client CA")
+		}
+
+		if debug.TLSConfig, err = cr.ServerConfig(false); err != nil {
+			return fmt.Errorf("configuring debug TLS: %w", err)
+		}
+
+	case cfg.TLS.ClientCAFile != "" || cfg.TLS.APIRequireClientCert || cfg.TLS.DebugRequireClientCert:
+		return errors.New("client certificates require a certificate and key file")
+	}
 
 	// -------------------------------------------------------------------------
-	// Shutdown
+	// HTTP/2 Support
 
-	select {
-	case err := <-serverErrors:
-		return fmt.Errorf("server error: %w", err)
-	case sig := <-shutdown:
-		log.Infow("shutdown", "status", "shutdown started", "signal", sig)
-		defer log.Infow("shutdown", "status", "shutdown complete", "signal", sig)
+	// Long-lived multiplexed connections are limited in the number of
+	// streams they can hold open at once. Over TLS the protocol is negotiated
+	// with the client. Without TLS it has to be turned on explicitly since
+	// only callers that know about it, such as a service mesh, can use it.
+	h2s := http2.Server{
+		MaxConcurrentStreams: cfg.HTTP2.MaxConcurrentStreams,
+		MaxReadFrameSize:     cfg.HTTP2.MaxReadFrameSize,
+		IdleTimeout:          cfg.Web.IdleTimeout,
+	}
 
-		ctx, cancel := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
-		defer cancel()
+	switch {
+	case api.TLSConfig != nil:
+		log.Infow("startup", "status", "initializing HTTP/2 support", "cleartext", false)
 
-		if err := api.Shutdown(ctx); err != nil {
-			api.Close()
-			return fmt.Errorf("could not stop server gracefully: %w", err)
+		if err := http2.ConfigureServer(&api, &h2s); err != nil {
+			return fmt.Errorf("configuring HTTP/2: %w", err)
 		}
 
+	case cfg.HTTP2.Cleartext:
+		log.Infow("startup", "status", "initializing HTTP/2 support", "cleartext", true)
+
+		api.Handler = h2c.NewHandler(api.Handler, &h2s)
 	}
 
-	return nil
+	// -------------------------------------------------------------------------
+	// Configuration Reload
+
+	log.Infow("startup", "status", "initializing config reloader")
+
+	// A SIGHUP reloads the configuration. It is kept on its own channel so
+	// it can never be mistaken for a request to shut down.
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+
+	reloader := config.NewReloader(log, &cfg, base, func(next any) error {
+		_, err := config.Parse(prefix, next, config.WithFile(configFile))
+		return err
+	})
+
+	// The subscribers run on the reloader goroutine, the only place the
+	// configuration is changed once the service is running. The level is
+	// only set when the configured one changed, so a reload for any other
+	// setting leaves a level changed through the debug endpoint alone.
+	reloader.Subscribe(func() {
+		l, err := zapcore.ParseLevel(cfg.Log.Level)
+		if err != nil {
+			log.Errorw("config reload", "status", "invalid log level", "ERROR", err)
+			return
+		}
+
+		if l == logLevel {
+			return
+		}
+
+		logLevel = l
+		lgh.Set(l)
+	})
+
+	reloader.Subscribe(func() {
+		apiMux.SetTimeouts(cfg.Web.ReadTimeout, cfg.Web.WriteTimeout)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// -------------------------------------------------------------------------
+	// Start and Shutdown
+
+	// Components are stopped in the reverse order they are added. The debug
+	// server goes first so the probes and profiling endpoints stay available
+	// while the api drains its in-flight requests. The database is closed
+	// last, once nothing can use it anymore.
+	lc := lifecycle.New(log)
+	lc.AddCloser("database", func(context.Context) error {
+		return db.Close()
+	})
+	lc.AddServer("debug", &debug)
+	lc.AddServer("api", &api)
+	lc.Add("config reloader",
+		func() error {
+			reloader.Run(ctx, reload, configFile, cfg.Config.WatchInterval)
+			return nil
+		},
+		func(context.Context) error {
+			cancel()
+			return nil
+		},
+	)
+
+	// -------------------------------------------------------------------------
+	// Binary Upgrade
+
+	// A SIGUSR2 starts the binary on disk as a new process that inherits the
+	// listening sockets. Once it reports it is ready, this process shuts down
+	// through the normal path and drains its in-flight requests. Only one
+	// upgrade is handed off per process.
+	upgradeSig := make(chan os.Signal, 1)
+	signal.Notify(upgradeSig, syscall.SIGUSR2)
+
+	lc.Add("upgrader",
+		func() error {
+			for {
+				select {
+				case sig := <-upgradeSig:
+					log.Infow("upgrade", "status", "upgrade started", "signal", sig)
+
+					uctx, ucancel := context.WithTimeout(ctx, cfg.Web.UpgradeTimeout)
+					p, err := upgrade.Start(uctx, lc.Listeners())
+					ucancel()
+
+					if err != nil {
+						log.Errorw("upgrade", "status", "upgrade failed", "ERROR", err)
+						continue
+					}
+
+					log.Infow("upgrade", "status", "new process ready", "pid", p.Pid)
+
+					select {
+					case shutdown <- syscall.SIGTERM:
+					default:
+					}
+
+					// The new process owns the sockets now. Another signal
+					// while this one drains must not start a second process,
+					// nor kill this one as SIGUSR2 does by default.
+					signal.Ignore(syscall.SIGUSR2)
+					<-ctx.Done()
+					return nil
+
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		},
+		func(context.Context) error {
+			cancel()
+			return nil
+		},
+	)
+
+	// When this process was started by an upgrade, the process it replaces
+	// is waiting for it to serve before shutting down.
+	go func() {
+		<-lc.Started()
+		if err := upgrade.Ready(); err != nil {
+			log.Errorw("startup", "status", "reporting upgrade ready", "ERROR", err)
+		}
+	}()
+
+	return lc.Run(shutdown, cfg.Web.ShutdownTimeout)
 }
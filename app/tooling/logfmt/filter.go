@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// levels ranks the levels written by zap from least to most severe.
+var levels = map[string]int{
+	"debug":  0,
+	"info":   1,
+	"warn":   2,
+	"error":  3,
+	"dpanic": 4,
+	"panic":  5,
+	"fatal":  6,
+}
+
+// filter decides which log lines are displayed. The zero value lets every
+// line through.
+type filter struct {
+	service string
+	level   string
+	since   time.Time
+	until   time.Time
+	traceID string
+	exprs   []expr
+}
+
+// empty reports whether the filter lets every line through, in which case
+// lines that are not structured logs are displayed as well.
+func (f filter) empty() bool {
+	return f.service == "" && f.level == "" && f.since.IsZero() && f.until.IsZero() &&
+		f.traceID == "" && len(f.exprs) == 0
+}
+
+// match reports whether the log entry passes every part of the filter.
+func (f filter) match(e entry) bool {
+	if f.service != "" && !strings.EqualFold(e.str("service"), f.service) {
+		return false
+	}
+
+	if f.level != "" {
+		rank, ok := levels[strings.ToLower(e.str("level"))]
+		if !ok || rank < levels[f.level] {
+			return false
+		}
+	}
+
+	if !f.since.IsZero() || !f.until.IsZero() {
+		ts, ok := e.time()
+		if !ok {
+			return false
+		}
+		if !f.since.IsZero() && ts.Before(f.since) {
+			return false
+		}
+		if !f.until.IsZero() && ts.After(f.until) {
+			return false
+		}
+	}
+
+	if f.traceID != "" && e.str("trace_id") != f.traceID {
+		return false
+	}
+
+	for _, ex := range f.exprs {
+		if !ex.match(e) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseLevel validates the minimum level provided on the command line.
+func parseLevel(level string) (string, error) {
+	level = strings.ToLower(level)
+	if _, ok := levels[level]; level != "" && !ok {
+		return "", fmt.Errorf("unknown level %q", level)
+	}
+	return level, nil
+}
+
+// parseTime accepts an absolute RFC3339 time or a duration which is taken
+// relative to now, so 15m means 15 minutes ago.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expecting RFC3339 or a duration", s)
+	}
+	return t, nil
+}
+
+// =============================================================================
+
+// The operators supported by field expressions. Two character operators are
+// listed first so they are matched before their one character prefixes.
+var operators = []string{">=", "<=", "!=", "!~", "=", ">", "<", "~"}
+
+// expr is a field expression such as status>=500 or path~/v1/users.
+//
+//	=  !=          equal, not equal
+//	>  >=  <  <=   numeric comparison, string comparison for non-numbers
+//	~  !~          regular expression match, no match
+type expr struct {
+	key   string
+	op    string
+	value string
+	num   float64
+	isNum bool
+	re    *regexp.Regexp
+}
+
+// parseExpr parses a field expression.
+func parseExpr(s string) (expr, error) {
+	i := strings.IndexAny(s, "=!<>~")
+	if i <= 0 {
+		return expr{}, fmt.Errorf("invalid expression %q, expecting key<op>value", s)
+	}
+
+	var op string
+	for _, o := range operators {
+		if strings.HasPrefix(s[i:], o) {
+			op = o
+			break
+		}
+	}
+	if op == "" {
+		return expr{}, fmt.Errorf("invalid operator in expression %q", s)
+	}
+
+	ex := expr{
+		key:   strings.TrimSpace(s[:i]),
+		op:    op,
+		value: strings.TrimSpace(s[i+len(op):]),
+	}
+
+	switch op {
+	case "~", "!~":
+		re, err := regexp.Compile(ex.value)
+		if err != nil {
+			return expr{}, fmt.Errorf("invalid regular expression in %q: %w", s, err)
+		}
+		ex.re = re
+
+	default:
+		if n, err := strconv.ParseFloat(ex.value, 64); err == nil {
+			ex.num = n
+			ex.isNum = true
+		}
+	}
+
+	return ex, nil
+}
+
+// match reports whether the entry satisfies the expression. An entry missing
+// the key never matches.
+func (ex expr) match(e entry) bool {
+	if _, ok := e.fields[ex.key]; !ok {
+		return false
+	}
+	v := e.str(ex.key)
+
+	switch ex.op {
+	case "~":
+		return ex.re.MatchString(v)
+	case "!~":
+		return !ex.re.MatchString(v)
+	}
+
+	cmp := strings.Compare(v, ex.value)
+	if ex.isNum {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return ex.op == "!="
+		}
+
+		switch {
+		case n < ex.num:
+			cmp = -1
+		case n > ex.num:
+			cmp = 1
+		default:
+			cmp = 0
+		}
+	}
+
+	switch ex.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+
+	return false
+}
+
+// =============================================================================
+
+// exprFlags collects the repeated -where flags.
+type exprFlags []expr
+
+func (ef *exprFlags) String() string {
+	s := make([]string, len(*ef))
+	for i, ex := range *ef {
+		s[i] = ex.key + ex.op + ex.value
+	}
+	return strings.Join(s, ",")
+}
+
+func (ef *exprFlags) Set(s string) error {
+	ex, err := parseExpr(s)
+	if err != nil {
+		return err
+	}
+	*ef = append(*ef, ex)
+	return nil
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The set of keys every log line written by the logger package carries, in
+// the order they are displayed.
+var knownKeys = []string{"service", "ts", "level", "trace_id", "caller", "msg"}
+
+// zeroTraceID is displayed when a log line was not written for a request.
+const zeroTraceID = "00000000-0000-0000-0000-000000000000"
+
+// entry represents a single structured log line.
+type entry struct {
+	raw    string
+	fields map[string]any
+}
+
+// str returns the value for the specified key as a string.
+func (e entry) str(key string) string {
+	v, ok := e.fields[key]
+	if !ok || v == nil {
+		return ""
+	}
+
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	return fmt.Sprintf("%v", v)
+}
+
+// traceID returns the trace id for the entry, I like always having a trace id
+// present in the logs.
+func (e entry) traceID() string {
+	if id := e.str("trace_id"); id != "" {
+		return id
+	}
+	return zeroTraceID
+}
+
+// time returns the timestamp of the entry. Both the ISO8601 encoding used by
+// the logger package and zap's default epoch encoding are supported.
+func (e entry) time() (time.Time, bool) {
+	switch v := e.fields["ts"].(type) {
+	case string:
+		for _, layout := range []string{"2006-01-02T15:04:05.000Z0700", time.RFC3339Nano} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)), true
+	}
+
+	return time.Time{}, false
+}
+
+// extraKeys returns the keys that are not part of every log line, sorted so
+// the output is stable since map ordering is random.
+func (e entry) extraKeys() []string {
+	keys := make([]string, 0, len(e.fields))
+
+next:
+	for k := range e.fields {
+		for _, known := range knownKeys {
+			if k == known {
+				continue next
+			}
+		}
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// =============================================================================
+
+// formatter writes log lines in a specific output format.
+type formatter interface {
+	format(w io.Writer, e entry) error
+	raw(w io.Writer, line string) error
+}
+
+// newFormatter returns the formatter for the specified format name.
+func newFormatter(name string, color bool) (formatter, error) {
+	switch name {
+	case "console":
+		return &consoleFormatter{color: color}, nil
+	case "logfmt":
+		return &logfmtFormatter{}, nil
+	case "json":
+		return &jsonFormatter{}, nil
+	case "table":
+		return &tableFormatter{}, nil
+	}
+
+	return nil, fmt.Errorf("unknown format %q, expecting console, logfmt, json or table", name)
+}
+
+// =============================================================================
+
+// consoleFormatter writes the known keys separated by colons followed by the
+// rest of the keys in key[value] form. The level is colored when enabled.
+type consoleFormatter struct {
+	color bool
+}
+
+func (f *consoleFormatter) format(w io.Writer, e entry) error {
+	var b strings.Builder
+
+	level := e.str("level")
+	if f.color {
+		level = colorize(level)
+	}
+
+	b.WriteString(fmt.Sprintf("%s: %s: %s: %s: %s: %s",
+		e.str("service"),
+		e.str("ts"),
+		level,
+		e.traceID(),
+		e.str("caller"),
+		e.str("msg"),
+	))
+
+	for _, k := range e.extraKeys() {
+		b.WriteString(fmt.Sprintf(": %s[%s]", k, e.str(k)))
+	}
+
+	_, err := fmt.Fprintln(w, b.String())
+	return err
+}
+
+func (f *consoleFormatter) raw(w io.Writer, line string) error {
+	_, err := fmt.Fprintln(w, line)
+	return err
+}
+
+// The ANSI colors used for the different levels.
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorYellow = "\x1b[33m"
+	colorBlue   = "\x1b[34m"
+	colorGray   = "\x1b[90m"
+)
+
+func colorize(level string) string {
+	var color string
+
+	switch strings.ToLower(level) {
+	case "debug":
+		color = colorGray
+	case "info":
+		color = colorBlue
+	case "warn":
+		color = colorYellow
+	case "error", "dpanic", "panic", "fatal":
+		color = colorRed
+	default:
+		return level
+	}
+
+	return color + level + colorReset
+}
+
+// =============================================================================
+
+// logfmtFormatter writes every key as key=value pairs, quoting values when
+// required so the output can be parsed by logfmt tooling.
+type logfmtFormatter struct{}
+
+func (f *logfmtFormatter) format(w io.Writer, e entry) error {
+	var b strings.Builder
+
+	for _, k := range knownKeys {
+		v := e.str(k)
+		if k == "trace_id" {
+			v = e.traceID()
+		}
+
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(k + "=" + logfmtValue(v))
+	}
+
+	for _, k := range e.extraKeys() {
+		b.WriteString(" " + k + "=" + logfmtValue(e.str(k)))
+	}
+
+	_, err := fmt.Fprintln(w, b.String())
+	return err
+}
+
+func (f *logfmtFormatter) raw(w io.Writer, line string) error {
+	_, err := fmt.Fprintln(w, "msg="+logfmtValue(line))
+	return err
+}
+
+func logfmtValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " =\"\t\r\n") {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// =============================================================================
+
+// jsonFormatter passes the original log lines through untouched so they can
+// be piped into other JSON tooling. Lines that are not JSON are dropped.
+type jsonFormatter struct{}
+
+func (f *jsonFormatter) format(w io.Writer, e entry) error {
+	_, err := fmt.Fprintln(w, e.raw)
+	return err
+}
+
+func (f *jsonFormatter) raw(w io.Writer, line string) error {
+	return nil
+}
+
+// =============================================================================
+
+// tableFormatter aligns the known keys in columns. Since the input is a stream
+// the column widths start at the typical width of each key and grow as wider
+// values are seen.
+type tableFormatter struct {
+	widths []int
+}
+
+// tableWidths holds the starting width of the columns for the known keys.
+var tableWidths = []int{9, 24, 5, 36, 24, 17}
+
+func (f *tableFormatter) format(w io.Writer, e entry) error {
+	if f.widths == nil {
+		f.widths = append([]int(nil), tableWidths...)
+
+		header := make([]string, len(knownKeys))
+		for i, k := range knownKeys {
+			header[i] = strings.ToUpper(k)
+		}
+		if err := f.row(w, header, ""); err != nil {
+			return err
+		}
+	}
+
+	cols := make([]string, len(knownKeys))
+	for i, k := range knownKeys {
+		cols[i] = e.str(k)
+		if k == "trace_id" {
+			cols[i] = e.traceID()
+		}
+	}
+
+	var extra strings.Builder
+	for _, k := range e.extraKeys() {
+		if extra.Len() > 0 {
+			extra.WriteByte(' ')
+		}
+		extra.WriteString(k + "=" + logfmtValue(e.str(k)))
+	}
+
+	return f.row(w, cols, extra.String())
+}
+
+func (f *tableFormatter) row(w io.Writer, cols []string, extra string) error {
+	var b strings.Builder
+
+	for i, col := range cols {
+		if len(col) > f.widths[i] {
+			f.widths[i] = len(col)
+		}
+		b.WriteString(col)
+		b.WriteString(strings.Repeat(" ", f.widths[i]-len(col)+2))
+	}
+	b.WriteString(extra)
+
+	_, err := fmt.Fprintln(w, strings.TrimRight(b.String(), " "))
+	return err
+}
+
+func (f *tableFormatter) raw(w io.Writer, line string) error {
+	_, err := fmt.Fprintln(w, line)
+	return err
+}
@@ -3,81 +3,114 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
-	"strings"
+	"os/signal"
+	"time"
 )
 
-var service string
+var (
+	service   string
+	format    string
+	mode      string
+	color     bool
+	level     string
+	since     string
+	until     string
+	traceID   string
+	where     exprFlags
+	showStats bool
+)
 
 func init() {
 	flag.StringVar(&service, "service", "", "filter which service to see")
+	flag.StringVar(&format, "format", "console", "output format: console, logfmt, json or table")
+	flag.StringVar(&mode, "mode", "stream", "stream writes every line, trace groups lines per request, summary reports per route latencies")
+	flag.BoolVar(&color, "color", os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout), "color the level in the console format")
+	flag.StringVar(&level, "level", "", "minimum level to see: debug, info, warn or error")
+	flag.StringVar(&since, "since", "", "only see lines logged after this RFC3339 time or duration ago")
+	flag.StringVar(&until, "until", "", "only see lines logged before this RFC3339 time or duration ago")
+	flag.StringVar(&traceID, "trace", "", "only see lines for this trace id")
+	flag.BoolVar(&showStats, "stats", false, "report how many lines were read, parsed, failed to parse and filtered on exit")
+	flag.Var(&where, "where", "only see lines matching the expression, e.g. statuscode>=500 or path~/v1/users (repeatable)")
 }
 
 func main() {
 	flag.Parse()
-	var b strings.Builder
-
-	service := strings.ToLower(service)
-
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		s := scanner.Text()
-
-		m := make(map[string]any)
-		err := json.Unmarshal([]byte(s), &m)
-		if err != nil {
-			if service == "" {
-				fmt.Println(s)
-			}
-			continue
-		}
-
-		// If a service filter was provided, check.
-		if service != "" && strings.ToLower(m["service"].(string)) != service {
-			continue
-		}
-
-		// I like always having a traceid present in the logs.
-		traceID := "00000000-0000-0000-0000-000000000000"
-		if v, ok := m["trace_id"]; ok {
-			traceID = fmt.Sprintf("%v", v)
-		}
-
-		// Build out the know portions of the log in the order
-		// I want them in.
-		b.Reset()
-		b.WriteString(fmt.Sprintf("%s: %s: %s: %s: %s: %s: ",
-			m["service"],
-			m["ts"],
-			m["level"],
-			traceID,
-			m["caller"],
-			m["msg"],
-		))
-
-		// Add the rest of the keys ignoring the ones we already
-		// added for the log.
-		for k, v := range m {
-			switch k {
-			case "service", "ts", "level", "trace_id", "caller", "msg":
-				continue
-			}
-
-			// It's nice to see the key[value] in this format
-			// especially since map ordering is random.
-			b.WriteString(fmt.Sprintf("%s[%v]: ", k, v))
-		}
-
-		// Write the new log format, removing the last :
-		out := b.String()
-		fmt.Println(out[:len(out)-2])
+
+	f, err := newFormatter(format, color)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	flt, err := newFilter(time.Now())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+
+	snk, err := newSink(mode, out, f)
+	if err != nil {
+		log.Fatalln(err)
 	}
 
-	if err := scanner.Err(); err != nil {
+	// Closing the input on interrupt lets the trace and summary modes write
+	// what they collected from a live stream before exiting.
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		os.Stdin.Close()
+	}()
+
+	st, err := process(os.Stdin, snk, flt)
+	if err != nil {
 		log.Println(err)
 	}
+
+	if showStats {
+		fmt.Fprintln(os.Stderr, st)
+	}
+}
+
+// isTerminal reports whether the file is a terminal, so the escape codes
+// for color don't end up in a file or another program's input.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// newFilter constructs the filter from the command line flags.
+func newFilter(now time.Time) (filter, error) {
+	lvl, err := parseLevel(level)
+	if err != nil {
+		return filter{}, err
+	}
+
+	from, err := parseTime(since, now)
+	if err != nil {
+		return filter{}, err
+	}
+
+	to, err := parseTime(until, now)
+	if err != nil {
+		return filter{}, err
+	}
+
+	flt := filter{
+		service: service,
+		level:   lvl,
+		since:   from,
+		until:   to,
+		traceID: traceID,
+		exprs:   where,
+	}
+
+	return flt, nil
 }
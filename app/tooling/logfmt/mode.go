@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// The messages the request logging middleware writes around every request.
+const (
+	msgStarted   = "request started"
+	msgCompleted = "request completed"
+)
+
+// sink receives the lines that passed the filter.
+type sink interface {
+	entry(e entry) error
+	raw(line string) error
+	done() error
+}
+
+// newSink returns the sink for the specified mode name.
+func newSink(mode string, out *bufio.Writer, f formatter) (sink, error) {
+	switch mode {
+	case "stream":
+		return &streamSink{out: out, f: f}, nil
+	case "trace":
+		return &traceSink{out: out, f: f, open: make(map[string]*traceBlock)}, nil
+	case "summary":
+		return &summarySink{out: out, routes: make(map[string]*routeStats)}, nil
+	}
+
+	return nil, fmt.Errorf("unknown mode %q, expecting stream, trace or summary", mode)
+}
+
+// latency returns the latency logged for a completed request. The logger
+// encodes durations as seconds but a duration string is accepted as well.
+func latency(e entry) (time.Duration, bool) {
+	switch v := e.fields["latency"].(type) {
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return 0, false
+		}
+		return time.Duration(f * float64(time.Second)), true
+
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, false
+		}
+		return d, true
+	}
+
+	return 0, false
+}
+
+// =============================================================================
+
+// streamSink writes every line as soon as it is read. Output is flushed after
+// every line so it keeps up with a live stream.
+type streamSink struct {
+	out *bufio.Writer
+	f   formatter
+}
+
+func (s *streamSink) entry(e entry) error {
+	if err := s.f.format(s.out, e); err != nil {
+		return err
+	}
+	return s.out.Flush()
+}
+
+func (s *streamSink) raw(line string) error {
+	if err := s.f.raw(s.out, line); err != nil {
+		return err
+	}
+	return s.out.Flush()
+}
+
+func (s *streamSink) done() error {
+	return s.out.Flush()
+}
+
+// =============================================================================
+
+// traceBlock holds the lines logged for a single request.
+type traceBlock struct {
+	seq     int
+	entries []entry
+}
+
+// traceSink groups lines by trace id and writes each request as one block
+// once the request completes. Lines that don't belong to a request are
+// dropped. Requests that never complete are written when the input ends.
+type traceSink struct {
+	out  *bufio.Writer
+	f    formatter
+	seq  int
+	open map[string]*traceBlock
+}
+
+func (s *traceSink) entry(e entry) error {
+	id := e.str("trace_id")
+	if id == "" || id == zeroTraceID {
+		return nil
+	}
+
+	b, ok := s.open[id]
+	if !ok {
+		s.seq++
+		b = &traceBlock{seq: s.seq}
+		s.open[id] = b
+	}
+	b.entries = append(b.entries, e)
+
+	if e.str("msg") != msgCompleted {
+		return nil
+	}
+
+	delete(s.open, id)
+	if err := s.write(id, b, true); err != nil {
+		return err
+	}
+	return s.out.Flush()
+}
+
+func (s *traceSink) raw(line string) error {
+	return nil
+}
+
+func (s *traceSink) done() error {
+	ids := make([]string, 0, len(s.open))
+	for id := range s.open {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return s.open[ids[i]].seq < s.open[ids[j]].seq
+	})
+
+	for _, id := range ids {
+		if err := s.write(id, s.open[id], false); err != nil {
+			return err
+		}
+	}
+
+	return s.out.Flush()
+}
+
+func (s *traceSink) write(id string, b *traceBlock, completed bool) error {
+	first := b.entries[0]
+	last := b.entries[len(b.entries)-1]
+
+	var method, path, status string
+	for _, e := range b.entries {
+		switch e.str("msg") {
+		case msgStarted, msgCompleted:
+			method, path = e.str("method"), e.str("path")
+			status = e.str("statuscode")
+		}
+	}
+
+	duration := "incomplete"
+	if completed {
+		d, ok := latency(last)
+		if !ok {
+			start, ok1 := first.time()
+			end, ok2 := last.time()
+			if ok1 && ok2 {
+				d, ok = end.Sub(start), true
+			}
+		}
+		if ok {
+			duration = d.String()
+		}
+	}
+
+	header := fmt.Sprintf("==> trace[%s]: %s %s: status[%s]: duration[%s]", id, method, path, status, duration)
+	if _, err := fmt.Fprintln(s.out, header); err != nil {
+		return err
+	}
+
+	for _, e := range b.entries {
+		if err := s.f.format(s.out, e); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintln(s.out)
+	return err
+}
+
+// =============================================================================
+
+// routeStats holds what is known about the requests for a single route.
+type routeStats struct {
+	count       int
+	clientError int
+	serverError int
+	latencies   []time.Duration
+}
+
+// summarySink collects the completed requests per route and writes the
+// request counts, error rates and latency percentiles when the input ends.
+type summarySink struct {
+	out    *bufio.Writer
+	routes map[string]*routeStats
+}
+
+func (s *summarySink) entry(e entry) error {
+	if e.str("msg") != msgCompleted {
+		return nil
+	}
+
+	key := e.str("method") + " " + route(e.str("path"))
+
+	rs, ok := s.routes[key]
+	if !ok {
+		rs = &routeStats{}
+		s.routes[key] = rs
+	}
+
+	rs.count++
+
+	status, _ := strconv.Atoi(e.str("statuscode"))
+	switch {
+	case status >= 500:
+		rs.serverError++
+	case status >= 400:
+		rs.clientError++
+	}
+
+	if d, ok := latency(e); ok {
+		rs.latencies = append(rs.latencies, d)
+	}
+
+	return nil
+}
+
+func (s *summarySink) raw(line string) error {
+	return nil
+}
+
+func (s *summarySink) done() error {
+	keys := make([]string, 0, len(s.routes))
+	for k := range s.routes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tw := tabwriter.NewWriter(s.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROUTE\tCOUNT\t4XX\t5XX\tERROR RATE\tP50\tP95\tP99")
+
+	for _, k := range keys {
+		rs := s.routes[k]
+
+		sort.Slice(rs.latencies, func(i, j int) bool {
+			return rs.latencies[i] < rs.latencies[j]
+		})
+
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.2f%%\t%s\t%s\t%s\n",
+			k,
+			rs.count,
+			rs.clientError,
+			rs.serverError,
+			100*float64(rs.serverError)/float64(rs.count),
+			percentile(rs.latencies, 50),
+			percentile(rs.latencies, 95),
+			percentile(rs.latencies, 99),
+		)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	return s.out.Flush()
+}
+
+// percentile returns the nearest-rank percentile of the sorted latencies.
+func percentile(sorted []time.Duration, p float64) string {
+	if len(sorted) == 0 {
+		return "-"
+	}
+
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	return sorted[i].String()
+}
+
+// idSegment matches path segments that identify a resource, like a uuid or a
+// number, so requests for different resources are reported as one route.
+var idSegment = regexp.MustCompile(`^([0-9]+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})$`)
+
+// route removes the query string and replaces identifiers in the path.
+func route(path string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if idSegment.MatchString(seg) {
+			segs[i] = ":id"
+		}
+	}
+
+	return strings.Join(segs, "/")
+}
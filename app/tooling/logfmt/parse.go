@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// parse converts a log line into an entry. Numbers are kept as json.Number
+// so large integers are displayed exactly as they were logged. Lines that
+// are not a JSON object are reported as not ok.
+func parse(line string) (entry, bool) {
+	d := json.NewDecoder(strings.NewReader(line))
+	d.UseNumber()
+
+	var m map[string]any
+	if err := d.Decode(&m); err != nil || m == nil {
+		return entry{}, false
+	}
+
+	return entry{raw: line, fields: m}, true
+}
+
+// stats keeps count of what happened to the lines read from the input.
+type stats struct {
+	lines    int
+	parsed   int
+	failed   int
+	filtered int
+}
+
+// String implements the fmt.Stringer interface.
+func (s stats) String() string {
+	return fmt.Sprintf("lines[%d]: parsed[%d]: failed[%d]: filtered[%d]", s.lines, s.parsed, s.failed, s.filtered)
+}
+
+// process reads the log lines from r, filters them and hands them to the
+// sink. Lines of any length are supported since stack traces and config
+// dumps can be far larger than a typical buffer.
+func process(r io.Reader, s sink, flt filter) (stats, error) {
+	var st stats
+
+	in := bufio.NewReader(r)
+	for {
+		line, err := in.ReadString('\n')
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			st.lines++
+
+			if serr := processLine(line, s, flt, &st); serr != nil {
+				return st, serr
+			}
+		}
+
+		if err != nil {
+			// The input is closed on interrupt so the sink still gets to
+			// write what it collected.
+			if err == io.EOF || errors.Is(err, os.ErrClosed) {
+				return st, s.done()
+			}
+			return st, err
+		}
+	}
+}
+
+func processLine(line string, s sink, flt filter, st *stats) error {
+	e, ok := parse(line)
+	if !ok {
+		st.failed++
+
+		if flt.empty() {
+			return s.raw(line)
+		}
+		return nil
+	}
+	st.parsed++
+
+	if !flt.match(e) {
+		st.filtered++
+		return nil
+	}
+
+	return s.entry(e)
+}
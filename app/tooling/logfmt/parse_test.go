@@ -0,0 +1,144 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// recordSink keeps what process hands to it.
+type recordSink struct {
+	entries []entry
+	raws    []string
+	closed  bool
+}
+
+func (s *recordSink) entry(e entry) error {
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *recordSink) raw(line string) error {
+	s.raws = append(s.raws, line)
+	return nil
+}
+
+func (s *recordSink) done() error {
+	s.closed = true
+	return nil
+}
+
+const (
+	apiLine   = `{"level":"info","ts":"2024-01-02T15:04:05.000Z","service":"SALES-API","msg":"request started","trace_id":"t1"}`
+	adminLine = `{"level":"info","ts":"2024-01-02T15:04:05.000Z","service":"SALES-ADMIN","msg":"migrate"}`
+	noSvcLine = `{"level":"info","ts":"2024-01-02T15:04:05.000Z","msg":"no service"}`
+	numSvc    = `{"level":"info","ts":"2024-01-02T15:04:05.000Z","service":42,"msg":"number service"}`
+)
+
+// startupLine is the config logged on startup, which conf formats over
+// several lines that the encoder escapes into a single one.
+var startupLine = `{"level":"info","ts":"2024-01-02T15:04:05.000Z","service":"SALES-API","msg":"startup","config":"--web-api-host=0.0.0.0:3000\n--web-debug-host=0.0.0.0:4000\n--db-user=postgres\n--db-password=xxxxxx"}`
+
+func TestProcess(t *testing.T) {
+	long := `{"level":"error","service":"SALES-API","msg":"panic","stack":"` + strings.Repeat("x", 70*1024) + `"}`
+
+	tests := []struct {
+		name     string
+		input    string
+		flt      filter
+		want     stats
+		entries  int
+		raws     []string
+		checkKey string
+	}{
+		{
+			name:    "no filter",
+			input:   strings.Join([]string{apiLine, "plain text", adminLine, "{not json", noSvcLine}, "\n"),
+			want:    stats{lines: 5, parsed: 3, failed: 2},
+			entries: 3,
+			raws:    []string{"plain text", "{not json"},
+		},
+		{
+			name:    "service filter",
+			input:   strings.Join([]string{apiLine, "plain text", adminLine, noSvcLine, numSvc}, "\n") + "\n",
+			flt:     filter{service: "sales-api"},
+			want:    stats{lines: 5, parsed: 4, failed: 1, filtered: 3},
+			entries: 1,
+		},
+		{
+			name:    "numeric service",
+			input:   numSvc + "\n",
+			flt:     filter{service: "42"},
+			want:    stats{lines: 1, parsed: 1},
+			entries: 1,
+		},
+		{
+			name:     "line over 64KiB",
+			input:    apiLine + "\n" + long + "\n" + adminLine + "\n",
+			want:     stats{lines: 3, parsed: 3},
+			entries:  3,
+			checkKey: "stack",
+		},
+		{
+			name:     "startup config",
+			input:    "\r\n" + startupLine + "\r\n\n",
+			want:     stats{lines: 1, parsed: 1},
+			entries:  1,
+			checkKey: "config",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s recordSink
+
+			st, err := process(strings.NewReader(tt.input), &s, tt.flt)
+			if err != nil {
+				t.Fatalf("process: %v", err)
+			}
+
+			if st != tt.want {
+				t.Errorf("stats: got %s, want %s", st, tt.want)
+			}
+
+			if !s.closed {
+				t.Error("sink not told the input ended")
+			}
+
+			if len(s.entries) != tt.entries {
+				t.Errorf("entries: got %d, want %d", len(s.entries), tt.entries)
+			}
+
+			if strings.Join(s.raws, "|") != strings.Join(tt.raws, "|") {
+				t.Errorf("raw lines: got %q, want %q", s.raws, tt.raws)
+			}
+
+			if tt.checkKey != "" {
+				found := false
+				for _, e := range s.entries {
+					if e.str(tt.checkKey) != "" {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("no entry with the %q key", tt.checkKey)
+				}
+			}
+		})
+	}
+}
+
+func TestParseStartupConfig(t *testing.T) {
+	e, ok := parse(startupLine)
+	if !ok {
+		t.Fatal("startup line not parsed")
+	}
+
+	cfg := e.str("config")
+	if got := strings.Count(cfg, "\n"); got != 3 {
+		t.Errorf("config lines: got %d newlines, want 3: %q", got, cfg)
+	}
+
+	if !strings.HasPrefix(cfg, "--web-api-host=") {
+		t.Errorf("config: got %q", cfg)
+	}
+}
@@ -0,0 +1,15 @@
+// Package commands contains the functionality for the set of commands
+// currently supported by the cli tooling.
+package commands
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrHelp provides context that help was given.
+var ErrHelp = errors.New("provided help")
+
+// timeout is the time a command that isn't configured with its own timeout
+// has to finish talking to the database.
+const timeout = 10 * time.Second
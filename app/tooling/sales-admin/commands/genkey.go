@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// GenKey creates an x509 private key for signing auth tokens. The key is
+// written to the keys folder in a file named after its key id.
+func GenKey(keysFolder string) error {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return fmt.Errorf("generating key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return fmt.Errorf("marshaling private key: %w", err)
+	}
+
+	if err := os.MkdirAll(keysFolder, 0o700); err != nil {
+		return fmt.Errorf("creating keys folder: %w", err)
+	}
+
+	kid := uuid.NewString()
+	file := filepath.Join(keysFolder, kid+".pem")
+
+	// O_EXCL makes sure an existing key is never overwritten.
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("creating private file: %w", err)
+	}
+	defer f.Close()
+
+	block := pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: der,
+	}
+
+	if err := pem.Encode(f, &block); err != nil {
+		return fmt.Errorf("encoding to private file: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing private file: %w", err)
+	}
+
+	fmt.Println("private key file:", file)
+	fmt.Println("kid:", kid)
+	return nil
+}
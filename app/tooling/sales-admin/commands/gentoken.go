@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/maxkulish/service-api/business/core/user"
+	"github.com/maxkulish/service-api/business/core/user/stores/userdb"
+	"github.com/maxkulish/service-api/business/data/sqldb"
+	"github.com/maxkulish/service-api/business/web/auth"
+	"github.com/maxkulish/service-api/foundation/keystore"
+	"go.uber.org/zap"
+)
+
+// GenToken generates a JWT for the specified user. The token carries the
+// roles the user has in the database.
+func GenToken(log *zap.SugaredLogger, dbCfg sqldb.Config, keysFolder string, authCfg auth.Config, ttl time.Duration, userID string) error {
+	if userID == "" {
+		fmt.Println("help: gentoken <user_id>")
+		return ErrHelp
+	}
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("parsing user id: %w", err)
+	}
+
+	ks, err := keystore.NewFS(os.DirFS(keysFolder))
+	if err != nil {
+		return fmt.Errorf("reading keys: %w", err)
+	}
+	authCfg.KeyLookup = ks
+
+	a, err := auth.New(authCfg)
+	if err != nil {
+		return fmt.Errorf("constructing auth: %w", err)
+	}
+
+	db, err := sqldb.Open(dbCfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	core := user.NewCore(userdb.NewStore(log, db))
+
+	usr, err := core.QueryByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return fmt.Errorf("user %s not found", id)
+		}
+		return fmt.Errorf("retrieving user: %w", err)
+	}
+
+	if !usr.Enabled {
+		return fmt.Errorf("user %s is disabled", id)
+	}
+
+	now := time.Now().UTC()
+	claims := auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   id.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Roles: usr.Roles,
+	}
+
+	token, err := a.GenerateToken(claims)
+	if err != nil {
+		return fmt.Errorf("generating token: %w", err)
+	}
+
+	fmt.Println(token)
+	return nil
+}
@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/maxkulish/service-api/business/data/dbmigrate"
+	"github.com/maxkulish/service-api/business/data/sqldb"
+	"go.uber.org/zap"
+)
+
+// Migrate creates the schema in the database.
+func Migrate(log *zap.SugaredLogger, cfg sqldb.Config, timeout time.Duration) error {
+	db, err := sqldb.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := dbmigrate.Migrate(ctx, log, db); err != nil {
+		return fmt.Errorf("migrate database: %w", err)
+	}
+
+	fmt.Println("migrations complete")
+	return nil
+}
@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/maxkulish/service-api/business/data/dbmigrate"
+	"github.com/maxkulish/service-api/business/data/sqldb"
+)
+
+// Seed loads test data into the database.
+func Seed(cfg sqldb.Config, timeout time.Duration) error {
+	db, err := sqldb.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := dbmigrate.Seed(ctx, db); err != nil {
+		return fmt.Errorf("seed database: %w", err)
+	}
+
+	fmt.Println("seed data complete")
+	return nil
+}
@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maxkulish/service-api/business/core/user"
+	"github.com/maxkulish/service-api/business/core/user/stores/userdb"
+	"github.com/maxkulish/service-api/business/data/sqldb"
+	"github.com/maxkulish/service-api/business/web/auth"
+	"go.uber.org/zap"
+)
+
+// UserAdd adds a new enabled user to the database. Without any roles the
+// user gets the USER role. The password is read from the environment
+// variable named by PasswordEnv, or from the first line of stdin, so it
+// doesn't end up in the shell history or the process list.
+func UserAdd(log *zap.SugaredLogger, cfg sqldb.Config, name, email string, roles []string) error {
+	if name == "" || email == "" {
+		fmt.Println("help: users add <name> <email> [role...]")
+		return ErrHelp
+	}
+
+	if err := user.ValidateEmail(email); err != nil {
+		return fmt.Errorf("invalid email %q: %w", email, err)
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+
+	if len(roles) == 0 {
+		roles = []string{auth.RoleUser}
+	}
+
+	userRoles := make([]string, len(roles))
+	for i, role := range roles {
+		role = strings.ToUpper(role)
+		if role != auth.RoleAdmin && role != auth.RoleUser {
+			return fmt.Errorf("invalid role %q", role)
+		}
+		userRoles[i] = role
+	}
+
+	db, err := sqldb.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	core := user.NewCore(userdb.NewStore(log, db))
+
+	nu := user.NewUser{
+		Name:     name,
+		Email:    email,
+		Roles:    userRoles,
+		Password: password,
+	}
+
+	usr, err := core.Create(ctx, nu)
+	if err != nil {
+		if errors.Is(err, user.ErrUniqueEmail) {
+			return fmt.Errorf("email %s is already in use", email)
+		}
+		return fmt.Errorf("adding user: %w", err)
+	}
+
+	fmt.Println("user id:", usr.ID)
+	return nil
+}
+
+// PasswordEnv names the environment variable UserAdd reads the password from.
+const PasswordEnv = "SALES_ADMIN_PASSWORD"
+
+// readPassword returns the password from PasswordEnv or the first line of
+// stdin, prompting for it when stdin is a terminal.
+func readPassword() (string, error) {
+	if password, ok := os.LookupEnv(PasswordEnv); ok {
+		if password == "" {
+			return "", fmt.Errorf("%s is empty", PasswordEnv)
+		}
+		return password, nil
+	}
+
+	if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprint(os.Stderr, "password: ")
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("reading password: %w", err)
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("no password provided on stdin or in %s", PasswordEnv)
+	}
+
+	return password, nil
+}
+
+// UserList prints the users in the database.
+func UserList(log *zap.SugaredLogger, cfg sqldb.Config) error {
+	db, err := sqldb.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	core := user.NewCore(userdb.NewStore(log, db))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tEMAIL\tROLES\tENABLED\tCREATED")
+
+	const rowsPerPage = 100
+	for page := 1; ; page++ {
+		users, err := core.Query(ctx, page, rowsPerPage)
+		if err != nil {
+			return fmt.Errorf("retrieving users: %w", err)
+		}
+
+		for _, usr := range users {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\n", usr.ID, usr.Name, usr.Email, strings.Join(usr.Roles, ","), usr.Enabled, usr.DateCreated.Format(time.RFC3339))
+		}
+
+		if len(users) < rowsPerPage {
+			break
+		}
+	}
+
+	return w.Flush()
+}
+
+// UserDisable stops the user with the specified id or email from logging in.
+func UserDisable(log *zap.SugaredLogger, cfg sqldb.Config, idOrEmail string) error {
+	if idOrEmail == "" {
+		fmt.Println("help: users disable <user_id|email>")
+		return ErrHelp
+	}
+
+	db, err := sqldb.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	core := user.NewCore(userdb.NewStore(log, db))
+
+	var usr user.User
+	if id, perr := uuid.Parse(idOrEmail); perr == nil {
+		usr, err = core.QueryByID(ctx, id)
+	} else {
+		usr, err = core.QueryByEmail(ctx, idOrEmail)
+	}
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return fmt.Errorf("user %s not found", idOrEmail)
+		}
+		return fmt.Errorf("retrieving user: %w", err)
+	}
+
+	enabled := false
+	if _, err := core.Update(ctx, usr, user.UpdateUser{Enabled: &enabled}); err != nil {
+		return fmt.Errorf("disabling user: %w", err)
+	}
+
+	fmt.Println("disabled user id:", usr.ID)
+	return nil
+}
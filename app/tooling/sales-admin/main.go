@@ -0,0 +1,171 @@
+// This program performs administrative tasks for the sales service.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/conf/v3"
+	"github.com/maxkulish/service-api/app/tooling/sales-admin/commands"
+	"github.com/maxkulish/service-api/business/data/sqldb"
+	"github.com/maxkulish/service-api/business/web/auth"
+	"github.com/maxkulish/service-api/foundation/config"
+	"github.com/maxkulish/service-api/foundation/logger"
+	"go.uber.org/zap"
+)
+
+var build = "develop"
+
+func main() {
+	// The logs go to stderr so the output of a command, like a token, can be
+	// used on its own.
+	log, err := logger.New("sales-admin", zap.NewAtomicLevelAt(zap.InfoLevel), "stderr")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer log.Sync()
+
+	if err := run(log); err != nil {
+		if !errors.Is(err, commands.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "error:", err)
+		}
+		log.Sync()
+		os.Exit(1)
+	}
+}
+
+func run(log *zap.SugaredLogger) error {
+	// -------------------------------------------------------------------------
+	// Configuration
+
+	// The settings share their names with the ones of sales-api, so both read
+	// the same environment variables, flags and config file.
+	cfg := struct {
+		conf.Version
+		Args   conf.Args
+		Config struct {
+			File string
+		}
+		DB struct {
+			User           string `conf:"default:postgres"`
+			Password       string `conf:"default:postgres,mask"`
+			Host           string `conf:"default:localhost:5432"`
+			Name           string `conf:"default:postgres"`
+			Schema         string
+			MaxIdleConns   int           `conf:"default:2"`
+			MaxOpenConns   int           `conf:"default:0"`
+			DisableTLS     bool          `conf:"default:true"`
+			MigrateTimeout time.Duration `conf:"default:60s"`
+		}
+		Auth struct {
+			KeysFolder string `conf:"default:zarf/keys/"`
+			ActiveKID  string
+			Issuer     string        `conf:"default:service project"`
+			TokenTTL   time.Duration `conf:"default:1h"`
+		}
+	}{
+		Version: conf.Version{
+			Build: build,
+			Desc:  "copyright information here",
+		},
+	}
+
+	// The config file may hold settings only sales-api uses.
+	const prefix = "SALES"
+	configFile := config.Path(prefix, os.Args[1:])
+	help, err := config.Parse(prefix, &cfg, config.WithFile(configFile).Partial())
+	if err != nil {
+		if errors.Is(err, conf.ErrHelpWanted) {
+			fmt.Println(help)
+			fmt.Println(usage)
+			return nil
+		}
+		return fmt.Errorf("parsing config: %w", err)
+	}
+
+	dbConfig := sqldb.Config{
+		User:         cfg.DB.User,
+		Password:     cfg.DB.Password,
+		Host:         cfg.DB.Host,
+		Name:         cfg.DB.Name,
+		Schema:       cfg.DB.Schema,
+		MaxIdleConns: cfg.DB.MaxIdleConns,
+		MaxOpenConns: cfg.DB.MaxOpenConns,
+		DisableTLS:   cfg.DB.DisableTLS,
+	}
+
+	authConfig := auth.Config{
+		ActiveKID: cfg.Auth.ActiveKID,
+		Issuer:    cfg.Auth.Issuer,
+	}
+
+	// -------------------------------------------------------------------------
+	// Commands
+
+	args := cfg.Args
+
+	switch args.Num(0) {
+	case "migrate":
+		return commands.Migrate(log, dbConfig, cfg.DB.MigrateTimeout)
+
+	case "seed":
+		return commands.Seed(dbConfig, cfg.DB.MigrateTimeout)
+
+	case "genkey":
+		return commands.GenKey(cfg.Auth.KeysFolder)
+
+	case "gentoken":
+		return commands.GenToken(log, dbConfig, cfg.Auth.KeysFolder, authConfig, cfg.Auth.TokenTTL, args.Num(1))
+
+	case "users":
+		switch args.Num(1) {
+		case "add":
+			var roles []string
+			if len(args) > 4 {
+				roles = args[4:]
+			}
+			return commands.UserAdd(log, dbConfig, args.Num(2), args.Num(3), roles)
+
+		case "list":
+			return commands.UserList(log, dbConfig)
+
+		case "disable":
+			return commands.UserDisable(log, dbConfig, args.Num(2))
+		}
+
+	case "config":
+		if args.Num(1) == "show" {
+			out, err := conf.String(&cfg)
+			if err != nil {
+				return fmt.Errorf("generating config for output: %w", err)
+			}
+			fmt.Println(out)
+			return nil
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, usage)
+
+	if len(args) == 0 {
+		return errors.New("no command provided")
+	}
+	return fmt.Errorf("unknown command %q", strings.Join(args, " "))
+}
+
+const usage = `Commands:
+  migrate                                   bring the database schema up to date
+  seed                                      load the development data set
+  genkey                                    create a private key for signing tokens
+  gentoken <user_id>                        generate a token for a user
+  users add <name> <email> [role...]        add a user, the password is read
+                                            from SALES_ADMIN_PASSWORD or stdin
+  users list
+  users disable <user_id|email>
+  config show                               print the configuration in use
+
+Settings are read from the same SALES_* environment variables, flags and
+config file as sales-api. Flags must come before the command.`
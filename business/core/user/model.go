@@ -0,0 +1,40 @@
+package user
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User represents information about an individual user.
+type User struct {
+	ID           uuid.UUID
+	Name         string
+	Email        string
+	Roles        []string
+	PasswordHash []byte
+	Department   string
+	Enabled      bool
+	DateCreated  time.Time
+	DateUpdated  time.Time
+}
+
+// NewUser contains information needed to create a new user.
+type NewUser struct {
+	Name       string
+	Email      string
+	Roles      []string
+	Department string
+	Password   string
+}
+
+// UpdateUser contains information needed to update a user. Fields that are
+// nil are left unchanged.
+type UpdateUser struct {
+	Name       *string
+	Email      *string
+	Roles      []string
+	Department *string
+	Password   *string
+	Enabled    *bool
+}
@@ -0,0 +1,63 @@
+package userdb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maxkulish/service-api/business/core/user"
+	"github.com/maxkulish/service-api/business/data/sqldb"
+)
+
+// dbUser represent the structure we need for moving data
+// between the app and the database.
+type dbUser struct {
+	ID           uuid.UUID         `db:"user_id"`
+	Name         string            `db:"name"`
+	Email        string            `db:"email"`
+	Roles        sqldb.StringArray `db:"roles"`
+	PasswordHash []byte            `db:"password_hash"`
+	Department   sql.NullString    `db:"department"`
+	Enabled      bool              `db:"enabled"`
+	DateCreated  time.Time         `db:"date_created"`
+	DateUpdated  time.Time         `db:"date_updated"`
+}
+
+func toDBUser(usr user.User) dbUser {
+	return dbUser{
+		ID:           usr.ID,
+		Name:         usr.Name,
+		Email:        usr.Email,
+		Roles:        usr.Roles,
+		PasswordHash: usr.PasswordHash,
+		Department: sql.NullString{
+			String: usr.Department,
+			Valid:  usr.Department != "",
+		},
+		Enabled:     usr.Enabled,
+		DateCreated: usr.DateCreated.UTC(),
+		DateUpdated: usr.DateUpdated.UTC(),
+	}
+}
+
+func toCoreUser(dbUsr dbUser) user.User {
+	return user.User{
+		ID:           dbUsr.ID,
+		Name:         dbUsr.Name,
+		Email:        dbUsr.Email,
+		Roles:        dbUsr.Roles,
+		PasswordHash: dbUsr.PasswordHash,
+		Department:   dbUsr.Department.String,
+		Enabled:      dbUsr.Enabled,
+		DateCreated:  dbUsr.DateCreated.In(time.Local),
+		DateUpdated:  dbUsr.DateUpdated.In(time.Local),
+	}
+}
+
+func toCoreUserSlice(dbUsers []dbUser) []user.User {
+	usrs := make([]user.User, len(dbUsers))
+	for i, dbUsr := range dbUsers {
+		usrs[i] = toCoreUser(dbUsr)
+	}
+	return usrs
+}
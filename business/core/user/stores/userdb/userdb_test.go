@@ -0,0 +1,123 @@
+package userdb_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/maxkulish/service-api/business/core/user"
+	"github.com/maxkulish/service-api/business/core/user/stores/userdb"
+	"github.com/maxkulish/service-api/business/data/dbtest"
+)
+
+// adminID is the id of the admin of the seed data.
+const adminID = "5cf37266-3473-4006-984f-9325122678b7"
+
+func TestStore(t *testing.T) {
+	log, db := dbtest.NewDB(t)
+	core := user.NewCore(userdb.NewStore(log, db))
+	ctx := context.Background()
+
+	admin, err := core.QueryByID(ctx, uuid.MustParse(adminID))
+	if err != nil {
+		t.Fatalf("query seeded admin: %s", err)
+	}
+	if admin.Email != "admin@example.com" || len(admin.Roles) != 2 {
+		t.Fatalf("unexpected seeded admin: %+v", admin)
+	}
+
+	usr, err := core.Create(ctx, user.NewUser{
+		Name:     "Bill Kennedy",
+		Email:    "Bill@Example.com",
+		Roles:    []string{"USER"},
+		Password: "gophers",
+	})
+	if err != nil {
+		t.Fatalf("create: %s", err)
+	}
+
+	t.Run("email case", func(t *testing.T) {
+		got, err := core.QueryByEmail(ctx, "BILL@example.COM")
+		if err != nil {
+			t.Fatalf("query by email: %s", err)
+		}
+		if got.ID != usr.ID || got.Email != "bill@example.com" {
+			t.Fatalf("got %s %q, want %s %q", got.ID, got.Email, usr.ID, "bill@example.com")
+		}
+	})
+
+	t.Run("duplicate email", func(t *testing.T) {
+		_, err := core.Create(ctx, user.NewUser{
+			Name:     "Bill Again",
+			Email:    "bill@EXAMPLE.com",
+			Roles:    []string{"USER"},
+			Password: "gophers",
+		})
+		if !errors.Is(err, user.ErrUniqueEmail) {
+			t.Fatalf("create: got %v, want %v", err, user.ErrUniqueEmail)
+		}
+
+		email := "ADMIN@example.com"
+		if _, err := core.Update(ctx, usr, user.UpdateUser{Email: &email}); !errors.Is(err, user.ErrUniqueEmail) {
+			t.Fatalf("update: got %v, want %v", err, user.ErrUniqueEmail)
+		}
+	})
+
+	t.Run("mixed case in the table", func(t *testing.T) {
+		// Rows written around the store still can't duplicate an email.
+		const q = `
+		INSERT INTO users (user_id, name, email, roles, password_hash, enabled, date_created, date_updated)
+		VALUES ($1, 'Shouty', 'BILL@EXAMPLE.COM', '{USER}', 'x', true, now(), now())`
+
+		if _, err := db.ExecContext(ctx, q, uuid.New()); err == nil {
+			t.Fatal("inserted an email that only differs in case")
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		name := "William Kennedy"
+		if _, err := core.Update(ctx, usr, user.UpdateUser{Name: &name}); err != nil {
+			t.Fatalf("update: %s", err)
+		}
+
+		got, err := core.QueryByID(ctx, usr.ID)
+		if err != nil {
+			t.Fatalf("query by id: %s", err)
+		}
+		if got.Name != name || !got.Enabled || len(got.Roles) != 1 {
+			t.Fatalf("unexpected user after update: %+v", got)
+		}
+	})
+
+	t.Run("query", func(t *testing.T) {
+		usrs, err := core.Query(ctx, 1, 2)
+		if err != nil {
+			t.Fatalf("query: %s", err)
+		}
+		if len(usrs) != 2 || usrs[0].Email != "admin@example.com" || usrs[1].Email != "bill@example.com" {
+			t.Fatalf("unexpected page: %+v", usrs)
+		}
+
+		n, err := core.Count(ctx)
+		if err != nil {
+			t.Fatalf("count: %s", err)
+		}
+		if n != 3 {
+			t.Fatalf("got %d users, want 3", n)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := core.Delete(ctx, usr); err != nil {
+			t.Fatalf("delete: %s", err)
+		}
+
+		if _, err := core.QueryByID(ctx, usr.ID); !errors.Is(err, user.ErrNotFound) {
+			t.Fatalf("query by id: got %v, want %v", err, user.ErrNotFound)
+		}
+		if _, err := core.QueryByEmail(ctx, "bill@example.com"); !errors.Is(err, user.ErrNotFound) {
+			t.Fatalf("query by email: got %v, want %v", err, user.ErrNotFound)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+// Package usermem contains an in memory user store. It keeps the rules the
+// database enforces, like unique emails, so the layers above can be tested
+// without one.
+package usermem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/maxkulish/service-api/business/core/user"
+)
+
+// Store manages the set of APIs for user access in memory.
+type Store struct {
+	mu    sync.RWMutex
+	users map[uuid.UUID]user.User
+}
+
+// NewStore constructs an empty store.
+func NewStore() *Store {
+	return &Store{
+		users: make(map[uuid.UUID]user.User),
+	}
+}
+
+// Create adds a new user to the store.
+func (s *Store) Create(ctx context.Context, usr user.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailTaken(usr) {
+		return fmt.Errorf("create: %w", user.ErrUniqueEmail)
+	}
+	s.users[usr.ID] = usr
+
+	return nil
+}
+
+// Update replaces a user in the store.
+func (s *Store) Update(ctx context.Context, usr user.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailTaken(usr) {
+		return fmt.Errorf("update: %w", user.ErrUniqueEmail)
+	}
+	if _, ok := s.users[usr.ID]; ok {
+		s.users[usr.ID] = usr
+	}
+
+	return nil
+}
+
+// Delete removes a user from the store.
+func (s *Store) Delete(ctx context.Context, usr user.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.users, usr.ID)
+
+	return nil
+}
+
+// Query retrieves a page of users ordered by their email.
+func (s *Store) Query(ctx context.Context, pageNumber int, rowsPerPage int) ([]user.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	usrs := make([]user.User, 0, len(s.users))
+	for _, usr := range s.users {
+		usrs = append(usrs, usr)
+	}
+	sort.Slice(usrs, func(i, j int) bool {
+		return usrs[i].Email < usrs[j].Email
+	})
+
+	start := (pageNumber - 1) * rowsPerPage
+	if start < 0 || start >= len(usrs) {
+		return nil, nil
+	}
+
+	return usrs[start:min(start+rowsPerPage, len(usrs))], nil
+}
+
+// Count returns the total number of users in the store.
+func (s *Store) Count(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.users), nil
+}
+
+// QueryByID gets the specified user from the store.
+func (s *Store) QueryByID(ctx context.Context, userID uuid.UUID) (user.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	usr, ok := s.users[userID]
+	if !ok {
+		return user.User{}, fmt.Errorf("querybyid: %w", user.ErrNotFound)
+	}
+
+	return usr, nil
+}
+
+// QueryByEmail gets the specified user from the store by email.
+func (s *Store) QueryByEmail(ctx context.Context, email string) (user.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, usr := range s.users {
+		if usr.Email == email {
+			return usr, nil
+		}
+	}
+
+	return user.User{}, fmt.Errorf("querybyemail: %w", user.ErrNotFound)
+}
+
+// emailTaken reports whether another user already has the email of usr.
+func (s *Store) emailTaken(usr user.User) bool {
+	for _, other := range s.users {
+		if other.ID != usr.ID && other.Email == usr.Email {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+// Package user provides the core business API for users. The rules that
+// don't depend on how users are stored, like hashing passwords, live here
+// while the storage itself is left to a Storer.
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound              = errors.New("user not found")
+	ErrUniqueEmail           = errors.New("email is not unique")
+	ErrAuthenticationFailure = errors.New("authentication failed")
+)
+
+// ValidateEmail accepts a bare address only, without a display name.
+func ValidateEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return errors.New("must be a valid email address")
+	}
+	return nil
+}
+
+// normalizeEmail returns the form emails are stored and looked up in, so
+// addresses that only differ in case belong to the same user.
+func normalizeEmail(email string) string {
+	return strings.ToLower(email)
+}
+
+// dummyHash is compared against when authenticating an unknown email. It
+// uses bcrypt.DefaultCost like the hashes of the users.
+var dummyHash = []byte("$2a$10$eOfG7tvSvnD7mWthASIQ/Opn5fUd18deM91qECx4NQjlwWaaQf4va")
+
+// Storer interface declares the behavior this package needs to persist and
+// retrieve data.
+type Storer interface {
+	Create(ctx context.Context, usr User) error
+	Update(ctx context.Context, usr User) error
+	Delete(ctx context.Context, usr User) error
+	Query(ctx context.Context, pageNumber int, rowsPerPage int) ([]User, error)
+	Count(ctx context.Context) (int, error)
+	QueryByID(ctx context.Context, userID uuid.UUID) (User, error)
+	QueryByEmail(ctx context.Context, email string) (User, error)
+}
+
+// Core manages the set of APIs for user access.
+type Core struct {
+	storer Storer
+}
+
+// NewCore constructs a core for user api access.
+func NewCore(storer Storer) *Core {
+	return &Core{
+		storer: storer,
+	}
+}
+
+// Create inserts a new user into the database.
+func (c *Core) Create(ctx context.Context, nu NewUser) (User, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(nu.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return User{}, fmt.Errorf("generatefrompassword: %w", err)
+	}
+
+	now := time.Now().UTC()
+
+	usr := User{
+		ID:           uuid.New(),
+		Name:         nu.Name,
+		Email:        normalizeEmail(nu.Email),
+		Roles:        nu.Roles,
+		PasswordHash: hash,
+		Department:   nu.Department,
+		Enabled:      true,
+		DateCreated:  now,
+		DateUpdated:  now,
+	}
+
+	if err := c.storer.Create(ctx, usr); err != nil {
+		return User{}, fmt.Errorf("create: %w", err)
+	}
+
+	return usr, nil
+}
+
+// Update replaces a user document in the database.
+func (c *Core) Update(ctx context.Context, usr User, uu UpdateUser) (User, error) {
+	if uu.Name != nil {
+		usr.Name = *uu.Name
+	}
+	if uu.Email != nil {
+		usr.Email = normalizeEmail(*uu.Email)
+	}
+	if uu.Roles != nil {
+		usr.Roles = uu.Roles
+	}
+	if uu.Department != nil {
+		usr.Department = *uu.Department
+	}
+	if uu.Password != nil {
+		pw, err := bcrypt.GenerateFromPassword([]byte(*uu.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return User{}, fmt.Errorf("generatefrompassword: %w", err)
+		}
+		usr.PasswordHash = pw
+	}
+	if uu.Enabled != nil {
+		usr.Enabled = *uu.Enabled
+	}
+	usr.DateUpdated = time.Now().UTC()
+
+	if err := c.storer.Update(ctx, usr); err != nil {
+		return User{}, fmt.Errorf("update: %w", err)
+	}
+
+	return usr, nil
+}
+
+// Delete removes a user from the database.
+func (c *Core) Delete(ctx context.Context, usr User) error {
+	if err := c.storer.Delete(ctx, usr); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a page of existing users from the database.
+func (c *Core) Query(ctx context.Context, pageNumber int, rowsPerPage int) ([]User, error) {
+	users, err := c.storer.Query(ctx, pageNumber, rowsPerPage)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return users, nil
+}
+
+// Count returns the total number of users in the database.
+func (c *Core) Count(ctx context.Context) (int, error) {
+	count, err := c.storer.Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("count: %w", err)
+	}
+
+	return count, nil
+}
+
+// QueryByID gets the specified user from the database.
+func (c *Core) QueryByID(ctx context.Context, userID uuid.UUID) (User, error) {
+	usr, err := c.storer.QueryByID(ctx, userID)
+	if err != nil {
+		return User{}, fmt.Errorf("query: userID[%s]: %w", userID, err)
+	}
+
+	return usr, nil
+}
+
+// QueryByEmail gets the specified user from the database by email.
+func (c *Core) QueryByEmail(ctx context.Context, email string) (User, error) {
+	email = normalizeEmail(email)
+
+	usr, err := c.storer.QueryByEmail(ctx, email)
+	if err != nil {
+		return User{}, fmt.Errorf("query: email[%s]: %w", email, err)
+	}
+
+	return usr, nil
+}
+
+// Authenticate finds a user by their email and verifies their password. On
+// success it returns the user. Otherwise it returns ErrAuthenticationFailure,
+// without telling an unknown email apart from a wrong password. A disabled
+// user can't authenticate.
+func (c *Core) Authenticate(ctx context.Context, email string, password string) (User, error) {
+	usr, err := c.QueryByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			// Comparing against a hash that matches nothing takes as long as
+			// a real comparison, so the response time doesn't tell which
+			// emails belong to a user.
+			bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+			return User{}, ErrAuthenticationFailure
+		}
+		return User{}, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword(usr.PasswordHash, []byte(password)); err != nil {
+		return User{}, ErrAuthenticationFailure
+	}
+
+	if !usr.Enabled {
+		return User{}, ErrAuthenticationFailure
+	}
+
+	return usr, nil
+}
@@ -0,0 +1,151 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/maxkulish/service-api/business/core/user"
+	"github.com/maxkulish/service-api/business/core/user/stores/usermem"
+)
+
+func newUser(email string) user.NewUser {
+	return user.NewUser{
+		Name:     "Bill Kennedy",
+		Email:    email,
+		Roles:    []string{"USER"},
+		Password: "gophers",
+	}
+}
+
+func TestEmailCase(t *testing.T) {
+	core := user.NewCore(usermem.NewStore())
+	ctx := context.Background()
+
+	usr, err := core.Create(ctx, newUser("Bill@Example.com"))
+	if err != nil {
+		t.Fatalf("create: %s", err)
+	}
+	if usr.Email != "bill@example.com" {
+		t.Fatalf("got email %q stored, want it in lower case", usr.Email)
+	}
+
+	got, err := core.QueryByEmail(ctx, "BILL@example.COM")
+	if err != nil {
+		t.Fatalf("query by email: %s", err)
+	}
+	if got.ID != usr.ID {
+		t.Fatalf("got user %s, want %s", got.ID, usr.ID)
+	}
+
+	if _, err := core.Authenticate(ctx, "bILL@EXAMPLE.com", "gophers"); err != nil {
+		t.Fatalf("authenticate: %s", err)
+	}
+}
+
+func TestUniqueEmail(t *testing.T) {
+	core := user.NewCore(usermem.NewStore())
+	ctx := context.Background()
+
+	if _, err := core.Create(ctx, newUser("bill@example.com")); err != nil {
+		t.Fatalf("create: %s", err)
+	}
+
+	if _, err := core.Create(ctx, newUser("BILL@example.com")); !errors.Is(err, user.ErrUniqueEmail) {
+		t.Fatalf("create: got %v, want %v", err, user.ErrUniqueEmail)
+	}
+
+	other, err := core.Create(ctx, newUser("jill@example.com"))
+	if err != nil {
+		t.Fatalf("create: %s", err)
+	}
+
+	email := "Bill@Example.com"
+	if _, err := core.Update(ctx, other, user.UpdateUser{Email: &email}); !errors.Is(err, user.ErrUniqueEmail) {
+		t.Fatalf("update: got %v, want %v", err, user.ErrUniqueEmail)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	core := user.NewCore(usermem.NewStore())
+	ctx := context.Background()
+
+	usr, err := core.Create(ctx, newUser("bill@example.com"))
+	if err != nil {
+		t.Fatalf("create: %s", err)
+	}
+
+	got, err := core.Authenticate(ctx, "bill@example.com", "gophers")
+	if err != nil {
+		t.Fatalf("authenticate: %s", err)
+	}
+	if got.ID != usr.ID {
+		t.Fatalf("got user %s, want %s", got.ID, usr.ID)
+	}
+
+	disabled, err := core.Create(ctx, newUser("jill@example.com"))
+	if err != nil {
+		t.Fatalf("create: %s", err)
+	}
+	enabled := false
+	if _, err := core.Update(ctx, disabled, user.UpdateUser{Enabled: &enabled}); err != nil {
+		t.Fatalf("update: %s", err)
+	}
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+	}{
+		{name: "wrong password", email: "bill@example.com", password: "gopher"},
+		{name: "empty password", email: "bill@example.com"},
+		{name: "unknown email", email: "nobody@example.com", password: "gophers"},
+		{name: "disabled", email: "jill@example.com", password: "gophers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := core.Authenticate(ctx, tt.email, tt.password); !errors.Is(err, user.ErrAuthenticationFailure) {
+				t.Fatalf("got %v, want %v", err, user.ErrAuthenticationFailure)
+			}
+		})
+	}
+}
+
+func TestUpdatePassword(t *testing.T) {
+	core := user.NewCore(usermem.NewStore())
+	ctx := context.Background()
+
+	usr, err := core.Create(ctx, newUser("bill@example.com"))
+	if err != nil {
+		t.Fatalf("create: %s", err)
+	}
+
+	password := "gophers2"
+	if _, err := core.Update(ctx, usr, user.UpdateUser{Password: &password}); err != nil {
+		t.Fatalf("update: %s", err)
+	}
+
+	if _, err := core.Authenticate(ctx, "bill@example.com", "gophers"); !errors.Is(err, user.ErrAuthenticationFailure) {
+		t.Errorf("old password: got %v, want %v", err, user.ErrAuthenticationFailure)
+	}
+	if _, err := core.Authenticate(ctx, "bill@example.com", password); err != nil {
+		t.Errorf("new password: %s", err)
+	}
+}
+
+func TestPasswordTooLong(t *testing.T) {
+	core := user.NewCore(usermem.NewStore())
+	ctx := context.Background()
+
+	nu := newUser("bill@example.com")
+	nu.Password = strings.Repeat("x", 73)
+	if _, err := core.Create(ctx, nu); err == nil {
+		t.Fatal("created a user with a password bcrypt would cut short")
+	}
+
+	if n, _ := core.Count(ctx); n != 0 {
+		t.Fatalf("got %d users stored, want none", n)
+	}
+}
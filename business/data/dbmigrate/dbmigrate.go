@@ -0,0 +1,215 @@
+// Package dbmigrate contains the database schema, the seed data used for
+// development and the support for bringing a database up to date with them.
+package dbmigrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/maxkulish/service-api/business/data/sqldb"
+	"go.uber.org/zap"
+)
+
+// The migrations are named <version>_<description>.sql and applied in the
+// order of their version. A file must not be changed once it has been
+// applied anywhere. Changes to the schema are made by adding a new file.
+//
+//go:embed sql/*.sql
+var migrationsFS embed.FS
+
+// lockID identifies the advisory lock held while migrating, so services
+// starting at the same time don't apply the same migration twice.
+const lockID = 7_320_105_118
+
+// ErrChecksumMismatch is returned when a migration that was already applied
+// has been changed since.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// migration is a single versioned change to the schema.
+type migration struct {
+	version     int
+	description string
+	checksum    string
+	query       string
+}
+
+// applied is a migration as recorded in the database.
+type applied struct {
+	Version  int    `db:"version"`
+	Checksum string `db:"checksum"`
+}
+
+// Migrate attempts to bring the database up to date with the migrations
+// defined in this package. Every migration runs in its own transaction
+// together with recording its version.
+func Migrate(ctx context.Context, log *zap.SugaredLogger, db *sqlx.DB) error {
+	if err := sqldb.StatusCheck(ctx, db); err != nil {
+		return fmt.Errorf("status check database: %w", err)
+	}
+
+	migrations, err := load(migrationsFS)
+	if err != nil {
+		return fmt.Errorf("loading migrations: %w", err)
+	}
+
+	// The advisory lock is held by a session, so every statement has to use
+	// the same connection from the pool.
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID); err != nil {
+			log.Errorw("migrate", "status", "releasing lock", "ERROR", err)
+		}
+	}()
+
+	const create = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version     INT       NOT NULL,
+		description TEXT      NOT NULL,
+		checksum    TEXT      NOT NULL,
+		applied_at  TIMESTAMP NOT NULL,
+
+		PRIMARY KEY (version)
+	)`
+
+	if _, err := conn.ExecContext(ctx, create); err != nil {
+		return fmt.Errorf("creating migrations table: %w", err)
+	}
+
+	var rows []applied
+	if err := sqlx.SelectContext(ctx, conn, &rows, `SELECT version, checksum FROM schema_migrations`); err != nil {
+		return fmt.Errorf("reading applied migrations: %w", err)
+	}
+
+	done := make(map[int]string, len(rows))
+	for _, row := range rows {
+		done[row.Version] = row.Checksum
+	}
+
+	todo, err := pending(migrations, done)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range todo {
+		log.Infow("migrate", "status", "applying migration", "version", m.version, "description", m.description)
+
+		if err := apply(ctx, conn, m); err != nil {
+			return fmt.Errorf("migration %d %q: %w", m.version, m.description, err)
+		}
+	}
+
+	return nil
+}
+
+// apply runs a migration and records it in the same transaction.
+func apply(ctx context.Context, conn *sqlx.Conn, m migration) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tran: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.query); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	const q = `
+	INSERT INTO schema_migrations
+		(version, description, checksum, applied_at)
+	VALUES
+		($1, $2, $3, $4)`
+
+	if _, err := tx.ExecContext(ctx, q, m.version, m.description, m.checksum, time.Now().UTC()); err != nil {
+		return fmt.Errorf("recording version: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tran: %w", err)
+	}
+
+	return nil
+}
+
+// pending returns the migrations that haven't been applied yet, in order.
+// done holds the checksums of the applied migrations by version. It fails
+// when an applied migration has been changed since.
+func pending(migrations []migration, done map[int]string) ([]migration, error) {
+	var todo []migration
+	for _, m := range migrations {
+		checksum, ok := done[m.version]
+		if !ok {
+			todo = append(todo, m)
+			continue
+		}
+		if checksum != m.checksum {
+			return nil, fmt.Errorf("migration %d %q: %w", m.version, m.description, ErrChecksumMismatch)
+		}
+	}
+
+	return todo, nil
+}
+
+// load reads the migrations in the sql folder of fsys ordered by version.
+func load(fsys fs.FS) ([]migration, error) {
+	names, err := fs.Glob(fsys, "sql/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]migration, 0, len(names))
+	seen := make(map[int]string, len(names))
+
+	for _, name := range names {
+		base := strings.TrimSuffix(path.Base(name), ".sql")
+
+		prefix, desc, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("%s: file name must start with a positive version number", name)
+		}
+
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("%s: version %d already used by %s", name, version, other)
+		}
+		seen[version] = name
+
+		query, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(query)
+
+		migrations = append(migrations, migration{
+			version:     version,
+			description: strings.ReplaceAll(desc, "_", " "),
+			checksum:    hex.EncodeToString(sum[:]),
+			query:       string(query),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
@@ -0,0 +1,151 @@
+package dbmigrate
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	file := func(query string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(query)}
+	}
+
+	tests := []struct {
+		name     string
+		fsys     fstest.MapFS
+		versions []int
+		descs    []string
+		wantErr  bool
+	}{
+		{
+			name: "ordered by version",
+			fsys: fstest.MapFS{
+				"sql/0010_add_index.sql":   file("CREATE INDEX"),
+				"sql/0002_add_sales.sql":   file("CREATE TABLE sales"),
+				"sql/0001_add_users.sql":   file("CREATE TABLE users"),
+				"sql/README.md":            file("not a migration"),
+				"other/0003_elsewhere.sql": file("not a migration"),
+			},
+			versions: []int{1, 2, 10},
+			descs:    []string{"add users", "add sales", "add index"},
+		},
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"sql/0001_add_users.sql": file("CREATE TABLE users"),
+				"sql/001_add_people.sql": file("CREATE TABLE people"),
+				"sql/0002_add_sales.sql": file("CREATE TABLE sales"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "no version",
+			fsys: fstest.MapFS{
+				"sql/add_users.sql": file("CREATE TABLE users"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "zero version",
+			fsys: fstest.MapFS{
+				"sql/0000_add_users.sql": file("CREATE TABLE users"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty",
+			fsys: fstest.MapFS{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := load(tt.fsys)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %d migrations, want an error", len(migrations))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load: %s", err)
+			}
+
+			if len(migrations) != len(tt.versions) {
+				t.Fatalf("got %d migrations, want %d", len(migrations), len(tt.versions))
+			}
+			for i, m := range migrations {
+				if m.version != tt.versions[i] || m.description != tt.descs[i] {
+					t.Errorf("migration %d: got %d %q, want %d %q", i, m.version, m.description, tt.versions[i], tt.descs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadChecksum(t *testing.T) {
+	load := func(query string) migration {
+		t.Helper()
+
+		migrations, err := load(fstest.MapFS{"sql/0001_add_users.sql": &fstest.MapFile{Data: []byte(query)}})
+		if err != nil {
+			t.Fatalf("load: %s", err)
+		}
+		return migrations[0]
+	}
+
+	a, b, c := load("CREATE TABLE users"), load("CREATE TABLE users"), load("CREATE TABLE users ()")
+	if a.checksum != b.checksum {
+		t.Error("the same query got different checksums")
+	}
+	if a.checksum == c.checksum {
+		t.Error("different queries got the same checksum")
+	}
+}
+
+func TestPending(t *testing.T) {
+	migrations := []migration{
+		{version: 1, description: "add users", checksum: "a"},
+		{version: 2, description: "add sales", checksum: "b"},
+		{version: 3, description: "add index", checksum: "c"},
+	}
+
+	tests := []struct {
+		name     string
+		done     map[int]string
+		versions []int
+		wantErr  error
+	}{
+		{name: "none applied", done: map[int]string{}, versions: []int{1, 2, 3}},
+		{name: "some applied", done: map[int]string{1: "a", 2: "b"}, versions: []int{3}},
+		{name: "gap", done: map[int]string{1: "a", 3: "c"}, versions: []int{2}},
+		{name: "all applied", done: map[int]string{1: "a", 2: "b", 3: "c"}},
+		{name: "unknown applied", done: map[int]string{1: "a", 2: "b", 3: "c", 4: "d"}},
+		{name: "changed", done: map[int]string{1: "a", 2: "x"}, wantErr: ErrChecksumMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todo, err := pending(migrations, tt.done)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if len(todo) != len(tt.versions) {
+				t.Fatalf("got %d pending migrations, want %d", len(todo), len(tt.versions))
+			}
+			for i, m := range todo {
+				if m.version != tt.versions[i] {
+					t.Errorf("pending %d: got version %d, want %d", i, m.version, tt.versions[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	if _, err := load(migrationsFS); err != nil {
+		t.Fatalf("load: %s", err)
+	}
+}
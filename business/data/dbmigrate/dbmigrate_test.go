@@ -0,0 +1,74 @@
+package dbmigrate_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maxkulish/service-api/business/data/dbmigrate"
+	"github.com/maxkulish/service-api/business/data/dbtest"
+	"go.uber.org/zap"
+)
+
+func TestMigrateTwice(t *testing.T) {
+	db := dbtest.NewSchema(t)
+	log := zap.NewNop().Sugar()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		if err := dbmigrate.Migrate(ctx, log, db); err != nil {
+			t.Fatalf("migrate %d: %s", i+1, err)
+		}
+	}
+
+	var applied, distinct int
+	if err := db.QueryRowContext(ctx, `SELECT count(*), count(DISTINCT version) FROM schema_migrations`).Scan(&applied, &distinct); err != nil {
+		t.Fatalf("counting migrations: %s", err)
+	}
+	if applied == 0 || applied != distinct {
+		t.Fatalf("got %d applied migrations with %d versions", applied, distinct)
+	}
+}
+
+// TestMigrateConcurrent runs migrations the way replicas starting together
+// do. Without the lock they race to apply the same versions and all but one
+// fail on the primary key.
+func TestMigrateConcurrent(t *testing.T) {
+	db := dbtest.NewSchema(t)
+	log := zap.NewNop().Sugar()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	const n = 4
+	errs := make(chan error, n)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- dbmigrate.Migrate(ctx, log, db)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("migrate: %s", err)
+		}
+	}
+
+	// The lock is released again, so it can be taken right away.
+	var locked bool
+	if err := db.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(7320105118)`).Scan(&locked); err != nil {
+		t.Fatalf("taking lock: %s", err)
+	}
+	if !locked {
+		t.Fatal("lock still held after migrating")
+	}
+}
@@ -0,0 +1,38 @@
+package dbmigrate
+
+import (
+	"context"
+	_ "embed" // Required for go:embed.
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/maxkulish/service-api/business/data/sqldb"
+)
+
+//go:embed seed.sql
+var seedSQL string
+
+// Seed loads the known data set used for local development and tests into
+// a migrated database. Rows that already exist are left untouched, so it is
+// safe to run more than once.
+func Seed(ctx context.Context, db *sqlx.DB) error {
+	if err := sqldb.StatusCheck(ctx, db); err != nil {
+		return fmt.Errorf("status check database: %w", err)
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tran: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, seedSQL); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tran: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,18 @@
+-- The seed data uses fixed ids so running it again leaves the rows that
+-- already exist untouched. Both users have the password "gophers".
+
+INSERT INTO users (user_id, name, email, roles, password_hash, department, enabled, date_created, date_updated) VALUES
+	('5cf37266-3473-4006-984f-9325122678b7', 'Admin Gopher', 'admin@example.com', '{ADMIN,USER}', '$2a$10$z3slMNQ7LDIq7Qepl8nP.eeRtNMvWxxUJPx/7uRz3vQsTgv9q/v3.', NULL, true, '2019-03-24 00:00:00', '2019-03-24 00:00:00'),
+	('45b5fbd3-755f-4379-8f07-a58d4a30fa2f', 'User Gopher', 'user@example.com', '{USER}', '$2a$10$z3slMNQ7LDIq7Qepl8nP.eeRtNMvWxxUJPx/7uRz3vQsTgv9q/v3.', NULL, true, '2019-03-24 00:00:00', '2019-03-24 00:00:00')
+	ON CONFLICT DO NOTHING;
+
+INSERT INTO products (product_id, user_id, name, cost, quantity, date_created, date_updated) VALUES
+	('a2b0639f-2cc6-44b8-b97b-15d69dbb511e', '5cf37266-3473-4006-984f-9325122678b7', 'Comic Books', 50, 42, '2019-01-01 00:00:01.000001', '2019-01-01 00:00:01.000001'),
+	('72f8b983-3eb4-48db-9ed0-e45cc6bd716b', '45b5fbd3-755f-4379-8f07-a58d4a30fa2f', 'McDonalds Toys', 75, 120, '2019-01-01 00:00:02.000001', '2019-01-01 00:00:02.000001')
+	ON CONFLICT DO NOTHING;
+
+INSERT INTO sales (sale_id, user_id, product_id, quantity, paid, date_created) VALUES
+	('98b6d4b8-f04b-4c79-8c2e-a0aef46854b7', '45b5fbd3-755f-4379-8f07-a58d4a30fa2f', 'a2b0639f-2cc6-44b8-b97b-15d69dbb511e', 2, 100, '2019-01-01 00:00:03.000001'),
+	('85f6fb09-eb05-4874-ae39-82d1a30fe0d7', '45b5fbd3-755f-4379-8f07-a58d4a30fa2f', 'a2b0639f-2cc6-44b8-b97b-15d69dbb511e', 5, 250, '2019-01-01 00:00:04.000001'),
+	('a235be9e-ab5d-44e6-a987-fa1c749264c7', '5cf37266-3473-4006-984f-9325122678b7', '72f8b983-3eb4-48db-9ed0-e45cc6bd716b', 3, 225, '2019-01-01 00:00:05.000001')
+	ON CONFLICT DO NOTHING;
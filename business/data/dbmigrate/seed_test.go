@@ -0,0 +1,42 @@
+package dbmigrate_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/maxkulish/service-api/business/data/dbmigrate"
+	"github.com/maxkulish/service-api/business/data/dbtest"
+)
+
+func TestSeedTwice(t *testing.T) {
+	_, db := dbtest.NewDB(t)
+	ctx := context.Background()
+
+	count := func() map[string]int {
+		counts := make(map[string]int)
+		for _, table := range []string{"users", "products", "sales"} {
+			var n int
+			if err := db.GetContext(ctx, &n, `SELECT count(*) FROM `+table); err != nil {
+				t.Fatalf("counting %s: %s", table, err)
+			}
+			counts[table] = n
+		}
+		return counts
+	}
+
+	before := count()
+
+	if err := dbmigrate.Seed(ctx, db); err != nil {
+		t.Fatalf("seeding again: %s", err)
+	}
+
+	after := count()
+	for table, n := range before {
+		if n == 0 {
+			t.Errorf("%s: no rows seeded", table)
+		}
+		if after[table] != n {
+			t.Errorf("%s: got %d rows after seeding again, want %d", table, after[table], n)
+		}
+	}
+}
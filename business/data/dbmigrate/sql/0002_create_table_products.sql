@@ -0,0 +1,12 @@
+CREATE TABLE products (
+	product_id   UUID           NOT NULL,
+	user_id      UUID           NOT NULL,
+	name         TEXT           NOT NULL,
+	cost         NUMERIC(10, 2) NOT NULL,
+	quantity     INT            NOT NULL,
+	date_created TIMESTAMP      NOT NULL,
+	date_updated TIMESTAMP      NOT NULL,
+
+	PRIMARY KEY (product_id),
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
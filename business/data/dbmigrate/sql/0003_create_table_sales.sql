@@ -0,0 +1,12 @@
+CREATE TABLE sales (
+	sale_id      UUID           NOT NULL,
+	user_id      UUID           NOT NULL,
+	product_id   UUID           NOT NULL,
+	quantity     INT            NOT NULL,
+	paid         NUMERIC(10, 2) NOT NULL,
+	date_created TIMESTAMP      NOT NULL,
+
+	PRIMARY KEY (sale_id),
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+	FOREIGN KEY (product_id) REFERENCES products(product_id) ON DELETE CASCADE
+);
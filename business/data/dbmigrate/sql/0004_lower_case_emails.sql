@@ -0,0 +1,6 @@
+-- Emails are stored in lower case so addresses that only differ in case
+-- belong to the same user. The index enforces it for every writer. This
+-- fails if such duplicates already exist, they have to be merged by hand.
+UPDATE users SET email = lower(email) WHERE email <> lower(email);
+
+CREATE UNIQUE INDEX users_email_lower ON users (lower(email));
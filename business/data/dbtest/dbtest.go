@@ -0,0 +1,121 @@
+// Package dbtest contains supporting code for running tests that hit a
+// real database. Those tests are skipped unless SALES_TEST_DB_HOST names
+// the host:port of a Postgres they can use, for example:
+//
+//	docker run --rm -d -p 5432:5432 -e POSTGRES_PASSWORD=postgres postgres:15.3
+//	SALES_TEST_DB_HOST=localhost:5432 go test ./...
+package dbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/maxkulish/service-api/business/data/dbmigrate"
+	"github.com/maxkulish/service-api/business/data/sqldb"
+	"go.uber.org/zap"
+)
+
+// HostEnv is the variable naming the database the tests can use.
+const HostEnv = "SALES_TEST_DB_HOST"
+
+// Config returns the configuration of the test database. The test is
+// skipped when no database is available.
+func Config(t *testing.T) sqldb.Config {
+	t.Helper()
+
+	host := os.Getenv(HostEnv)
+	if host == "" {
+		t.Skipf("%s not set, skipping database test", HostEnv)
+	}
+
+	return sqldb.Config{
+		User:         envOr("SALES_TEST_DB_USER", "postgres"),
+		Password:     envOr("SALES_TEST_DB_PASSWORD", "postgres"),
+		Host:         host,
+		Name:         envOr("SALES_TEST_DB_NAME", "postgres"),
+		MaxIdleConns: 2,
+		DisableTLS:   true,
+	}
+}
+
+// NewSchema opens a connection to an empty schema of its own, so tests
+// don't see each other's data. The schema is dropped when the test ends.
+func NewSchema(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	cfg := Config(t)
+
+	admin, err := sqldb.Open(cfg)
+	if err != nil {
+		t.Fatalf("opening database: %s", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := sqldb.StatusCheck(ctx, admin); err != nil {
+		t.Fatalf("status check database: %s", err)
+	}
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("generating schema name: %s", err)
+	}
+	schema := "test_" + hex.EncodeToString(b)
+
+	if _, err := admin.ExecContext(ctx, `CREATE SCHEMA `+schema); err != nil {
+		t.Fatalf("creating schema: %s", err)
+	}
+
+	cfg.Schema = schema
+	db, err := sqldb.Open(cfg)
+	if err != nil {
+		t.Fatalf("opening database: %s", err)
+	}
+
+	// Cleanups run last in first out, so the schema is dropped after the
+	// connections using it are closed.
+	t.Cleanup(func() {
+		db.Close()
+		if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+			t.Errorf("dropping schema %s: %s", schema, err)
+		}
+	})
+
+	return db
+}
+
+// NewDB opens a connection to a schema of its own that is migrated and
+// seeded. It returns a logger for the stores under test along with it.
+func NewDB(t *testing.T) (*zap.SugaredLogger, *sqlx.DB) {
+	t.Helper()
+
+	db := NewSchema(t)
+	log := zap.NewNop().Sugar()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := dbmigrate.Migrate(ctx, log, db); err != nil {
+		t.Fatalf("migrating database: %s", err)
+	}
+
+	if err := dbmigrate.Seed(ctx, db); err != nil {
+		t.Fatalf("seeding database: %s", err)
+	}
+
+	return log, db
+}
+
+func envOr(key string, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
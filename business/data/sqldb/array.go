@@ -0,0 +1,28 @@
+package sqldb
+
+import (
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// typeMaps holds the maps that know how to decode the Postgres types
+// database/sql can't. A pgtype.Map caches what it learns about the types it
+// decodes and is not safe for concurrent use, so every scan borrows its own.
+var typeMaps = sync.Pool{
+	New: func() any {
+		return pgtype.NewMap()
+	},
+}
+
+// StringArray represents a text[] column. The driver writes a string slice
+// as an array on its own but database/sql can only read it back as a string.
+type StringArray []string
+
+// Scan implements the sql.Scanner interface.
+func (a *StringArray) Scan(src any) error {
+	m := typeMaps.Get().(*pgtype.Map)
+	defer typeMaps.Put(m)
+
+	return m.SQLScanner((*[]string)(a)).Scan(src)
+}
@@ -0,0 +1,71 @@
+package sqldb_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/maxkulish/service-api/business/data/sqldb"
+)
+
+func TestStringArrayScan(t *testing.T) {
+	tests := []struct {
+		name string
+		src  any
+		want []string
+	}{
+		{name: "text", src: "{ADMIN,USER}", want: []string{"ADMIN", "USER"}},
+		{name: "bytes", src: []byte("{USER}"), want: []string{"USER"}},
+		{name: "quoted", src: `{"a b","c,d"}`, want: []string{"a b", "c,d"}},
+		{name: "empty", src: "{}", want: []string{}},
+		{name: "null", src: nil, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a sqldb.StringArray
+			if err := a.Scan(tt.src); err != nil {
+				t.Fatalf("scan: %v", err)
+			}
+
+			if fmt.Sprintf("%q", a) != fmt.Sprintf("%q", tt.want) || (a == nil) != (tt.want == nil) {
+				t.Fatalf("got %q, want %q", a, tt.want)
+			}
+		})
+	}
+}
+
+// Rows are scanned from every request goroutine at once, run with -race.
+func TestStringArrayScanConcurrent(t *testing.T) {
+	const goroutines = 64
+
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines)
+
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				var a sqldb.StringArray
+				if err := a.Scan(fmt.Sprintf("{role%d,USER}", i)); err != nil {
+					errs <- err
+					return
+				}
+
+				if len(a) != 2 || a[0] != fmt.Sprintf("role%d", i) {
+					errs <- fmt.Errorf("goroutine %d: got %q", i, a)
+					return
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
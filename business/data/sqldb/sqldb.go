@@ -0,0 +1,228 @@
+// Package sqldb provides support for access to a Postgres compatible
+// database through a pool of connections.
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib" // Registers the pgx driver.
+	"github.com/jmoiron/sqlx"
+	"github.com/maxkulish/service-api/foundation/logger"
+	"go.uber.org/zap"
+)
+
+// Postgres error codes the package translates into its own errors.
+const (
+	uniqueViolation = "23505"
+	undefinedTable  = "42P01"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrDBNotFound        = errors.New("not found")
+	ErrDBDuplicatedEntry = errors.New("duplicated entry")
+	ErrUndefinedTable    = errors.New("undefined table")
+)
+
+// Config is the required properties to use the database.
+type Config struct {
+	User         string
+	Password     string
+	Host         string
+	Name         string
+	Schema       string
+	MaxIdleConns int
+	MaxOpenConns int
+	DisableTLS   bool
+}
+
+// Open knows how to open a database connection based on the configuration.
+// No connection is made until the pool is used, so a database that isn't up
+// yet doesn't stop the service from starting. Use StatusCheck to wait for it.
+func Open(cfg Config) (*sqlx.DB, error) {
+	sslMode := "require"
+	if cfg.DisableTLS {
+		sslMode = "disable"
+	}
+
+	q := make(url.Values)
+	q.Set("sslmode", sslMode)
+	q.Set("timezone", "utc")
+	if cfg.Schema != "" {
+		q.Set("search_path", cfg.Schema)
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     cfg.Host,
+		Path:     cfg.Name,
+		RawQuery: q.Encode(),
+	}
+
+	db, err := sqlx.Open("pgx", u.String())
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+
+	return db, nil
+}
+
+// StatusCheck returns nil if it can successfully talk to the database. It
+// keeps trying until the database answers or the context is done. Without
+// a deadline on the context it gives up after a second.
+func StatusCheck(ctx context.Context, db *sqlx.DB) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Second)
+		defer cancel()
+	}
+
+	for attempts := 1; ; attempts++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			break
+		}
+
+		select {
+		case <-time.After(time.Duration(attempts) * 100 * time.Millisecond):
+		case <-ctx.Done():
+			return fmt.Errorf("ping: %w", err)
+		}
+	}
+
+	// Run a simple query to determine connectivity. Running this query
+	// forces a round trip through the database.
+	const q = `SELECT true`
+	var tmp bool
+	return db.QueryRowContext(ctx, q).Scan(&tmp)
+}
+
+// WithinTran runs fn inside a transaction. The transaction is committed when
+// fn returns nil and rolled back otherwise.
+func WithinTran(ctx context.Context, log *zap.SugaredLogger, db *sqlx.DB, fn func(*sqlx.Tx) error) error {
+	log = logger.WithTrace(ctx, log)
+
+	log.Debugw("begin tran")
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tran: %w", err)
+	}
+
+	// A panic or an error from fn leaves the transaction open, so it is
+	// always rolled back unless the commit went through.
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			log.Errorw("unable to rollback tran", "ERROR", err)
+		}
+		log.Debugw("rollback tran")
+	}()
+
+	if err := fn(tx); err != nil {
+		return fmt.Errorf("exec tran: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tran: %w", err)
+	}
+	committed = true
+	log.Debugw("commit tran")
+
+	return nil
+}
+
+// NamedExecContext is a helper function to execute a CUD operation with
+// logging and tracing where field replacement is necessary.
+func NamedExecContext(ctx context.Context, log *zap.SugaredLogger, db sqlx.ExtContext, query string, data any) error {
+	logger.WithTrace(ctx, log).Debugw("database.NamedExecContext", "query", query)
+
+	if _, err := sqlx.NamedExecContext(ctx, db, query, data); err != nil {
+		return translate(err)
+	}
+
+	return nil
+}
+
+// NamedQuerySlice is a helper function for executing queries that return a
+// collection of data to be unmarshalled into a slice where field replacement
+// is necessary.
+func NamedQuerySlice[T any](ctx context.Context, log *zap.SugaredLogger, db sqlx.ExtContext, query string, data any, dest *[]T) error {
+	logger.WithTrace(ctx, log).Debugw("database.NamedQuerySlice", "query", query)
+
+	rows, err := sqlx.NamedQueryContext(ctx, db, query, data)
+	if err != nil {
+		return translate(err)
+	}
+	defer rows.Close()
+
+	var slice []T
+	for rows.Next() {
+		v := new(T)
+		if err := rows.StructScan(v); err != nil {
+			return err
+		}
+		slice = append(slice, *v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return translate(err)
+	}
+
+	*dest = slice
+
+	return nil
+}
+
+// NamedQueryStruct is a helper function for executing queries that return a
+// single value to be unmarshalled into a struct type where field replacement
+// is necessary. ErrDBNotFound is returned when there is no row.
+func NamedQueryStruct(ctx context.Context, log *zap.SugaredLogger, db sqlx.ExtContext, query string, data any, dest any) error {
+	logger.WithTrace(ctx, log).Debugw("database.NamedQueryStruct", "query", query)
+
+	rows, err := sqlx.NamedQueryContext(ctx, db, query, data)
+	if err != nil {
+		return translate(err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return translate(err)
+		}
+		return ErrDBNotFound
+	}
+
+	if err := rows.StructScan(dest); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// translate replaces the database errors callers need to act on with the
+// errors of this package, keeping the original in the chain.
+func translate(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case uniqueViolation:
+			return fmt.Errorf("%w: %w", ErrDBDuplicatedEntry, err)
+		case undefinedTable:
+			return fmt.Errorf("%w: %w", ErrUndefinedTable, err)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,116 @@
+package sqldb_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maxkulish/service-api/business/data/dbtest"
+	"github.com/maxkulish/service-api/business/data/sqldb"
+	"go.uber.org/zap"
+)
+
+func TestStatusCheck(t *testing.T) {
+	cfg := dbtest.Config(t)
+
+	db, err := sqldb.Open(cfg)
+	if err != nil {
+		t.Fatalf("open: %s", err)
+	}
+	defer db.Close()
+
+	if err := sqldb.StatusCheck(context.Background(), db); err != nil {
+		t.Fatalf("status check: %s", err)
+	}
+
+	// Nothing listens on port 1, so the check has to give up when the
+	// context is done.
+	cfg.Host = "127.0.0.1:1"
+	down, err := sqldb.Open(cfg)
+	if err != nil {
+		t.Fatalf("open: %s", err)
+	}
+	defer down.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	if err := sqldb.StatusCheck(ctx, down); err == nil {
+		t.Fatal("status check of an unreachable database succeeded")
+	}
+}
+
+type row struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+func TestNamedQuery(t *testing.T) {
+	db := dbtest.NewSchema(t)
+	log := zap.NewNop().Sugar()
+	ctx := context.Background()
+
+	const create = `CREATE TABLE items (id INT PRIMARY KEY, name TEXT NOT NULL UNIQUE)`
+	if _, err := db.ExecContext(ctx, create); err != nil {
+		t.Fatalf("create table: %s", err)
+	}
+
+	const insert = `INSERT INTO items (id, name) VALUES (:id, :name)`
+	for _, r := range []row{{1, "one"}, {2, "two"}} {
+		if err := sqldb.NamedExecContext(ctx, log, db, insert, r); err != nil {
+			t.Fatalf("insert %d: %s", r.ID, err)
+		}
+	}
+
+	t.Run("duplicate", func(t *testing.T) {
+		err := sqldb.NamedExecContext(ctx, log, db, insert, row{ID: 3, Name: "one"})
+		if !errors.Is(err, sqldb.ErrDBDuplicatedEntry) {
+			t.Fatalf("got %v, want %v", err, sqldb.ErrDBDuplicatedEntry)
+		}
+	})
+
+	t.Run("slice", func(t *testing.T) {
+		const q = `SELECT id, name FROM items WHERE id >= :id ORDER BY id`
+
+		var rows []row
+		if err := sqldb.NamedQuerySlice(ctx, log, db, q, row{ID: 1}, &rows); err != nil {
+			t.Fatalf("query: %s", err)
+		}
+		if len(rows) != 2 || rows[0].Name != "one" || rows[1].Name != "two" {
+			t.Fatalf("got %v", rows)
+		}
+	})
+
+	t.Run("struct", func(t *testing.T) {
+		const q = `SELECT id, name FROM items WHERE id = :id`
+
+		var r row
+		if err := sqldb.NamedQueryStruct(ctx, log, db, q, row{ID: 2}, &r); err != nil {
+			t.Fatalf("query: %s", err)
+		}
+		if r.Name != "two" {
+			t.Fatalf("got name %q, want %q", r.Name, "two")
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		const q = `SELECT id, name FROM items WHERE id = :id`
+
+		var r row
+		err := sqldb.NamedQueryStruct(ctx, log, db, q, row{ID: 42}, &r)
+		if !errors.Is(err, sqldb.ErrDBNotFound) {
+			t.Fatalf("got %v, want %v", err, sqldb.ErrDBNotFound)
+		}
+	})
+
+	t.Run("undefined table", func(t *testing.T) {
+		const q = `SELECT id, name FROM missing WHERE id = :id`
+
+		var r row
+		err := sqldb.NamedQueryStruct(ctx, log, db, q, row{ID: 1}, &r)
+		if !errors.Is(err, sqldb.ErrUndefinedTable) {
+			t.Fatalf("got %v, want %v", err, sqldb.ErrUndefinedTable)
+		}
+	})
+}
@@ -0,0 +1,175 @@
+// Package auth provides authentication and authorization support.
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrForbidden is returned when the claims don't allow an action.
+var ErrForbidden = errors.New("attempted action is not allowed")
+
+// These are the expected values for Claims.Roles.
+const (
+	RoleAdmin = "ADMIN"
+	RoleUser  = "USER"
+)
+
+// Claims represents the authorization claims transmitted via a JWT.
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles"`
+}
+
+// HasRole reports whether the claims hold at least one of the roles.
+func (c Claims) HasRole(roles ...string) bool {
+	for _, has := range c.Roles {
+		for _, want := range roles {
+			if has == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// KeyLookup declares a method set of behavior for looking up
+// private keys for JWT use.
+type KeyLookup interface {
+	PrivateKey(kid string) (*rsa.PrivateKey, error)
+}
+
+// Config represents information required to initialize auth.
+type Config struct {
+	KeyLookup KeyLookup
+	ActiveKID string
+	Issuer    string
+}
+
+// Auth is used to authenticate clients. It can generate a token for a
+// set of user claims and validate the tokens it generated.
+type Auth struct {
+	keyLookup KeyLookup
+	activeKID string
+	issuer    string
+	method    jwt.SigningMethod
+}
+
+// New creates an Auth to support authentication/authorization. The key
+// identified by the active kid signs every token.
+func New(cfg Config) (*Auth, error) {
+	if cfg.ActiveKID == "" {
+		return nil, errors.New("active kid not configured")
+	}
+
+	// The active key must exist, otherwise no token could be generated.
+	if _, err := cfg.KeyLookup.PrivateKey(cfg.ActiveKID); err != nil {
+		return nil, fmt.Errorf("active kid: %w", err)
+	}
+
+	a := Auth{
+		keyLookup: cfg.KeyLookup,
+		activeKID: cfg.ActiveKID,
+		issuer:    cfg.Issuer,
+		method:    jwt.GetSigningMethod(jwt.SigningMethodRS256.Name),
+	}
+
+	return &a, nil
+}
+
+// GenerateToken generates a signed JWT token string representing the user
+// Claims. The issuer of the claims is set to the one Auth was configured with.
+func (a *Auth) GenerateToken(claims Claims) (string, error) {
+	claims.Issuer = a.issuer
+
+	token := jwt.NewWithClaims(a.method, claims)
+	token.Header["kid"] = a.activeKID
+
+	privateKey, err := a.keyLookup.PrivateKey(a.activeKID)
+	if err != nil {
+		return "", fmt.Errorf("private key: %w", err)
+	}
+
+	str, err := token.SignedString(privateKey)
+	if err != nil {
+		return "", fmt.Errorf("signing token: %w", err)
+	}
+
+	return str, nil
+}
+
+// Authenticate validates the token of an Authorization header value in the
+// form "Bearer <token>" and returns its claims. The token must be signed
+// by one of the known keys, issued by the configured issuer and not expired.
+func (a *Auth) Authenticate(bearerToken string) (Claims, error) {
+	scheme, tokenStr, ok := strings.Cut(bearerToken, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || tokenStr == "" {
+		return Claims{}, errors.New("expected authorization header format: Bearer <token>")
+	}
+
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{a.method.Alg()}))
+
+	var claims Claims
+	if _, err := parser.ParseWithClaims(tokenStr, &claims, a.publicKey); err != nil {
+		return Claims{}, fmt.Errorf("parsing token: %w", err)
+	}
+
+	if claims.Issuer != a.issuer {
+		return Claims{}, errors.New("token issued by an unknown issuer")
+	}
+
+	if claims.Subject == "" {
+		return Claims{}, errors.New("token has no subject")
+	}
+
+	return claims, nil
+}
+
+// Authorize returns ErrForbidden unless the claims hold one of the roles.
+func (a *Auth) Authorize(claims Claims, roles ...string) error {
+	if !claims.HasRole(roles...) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// publicKey looks up the key that verifies a token by the kid in its header.
+func (a *Auth) publicKey(t *jwt.Token) (any, error) {
+	kid, ok := t.Header["kid"].(string)
+	if !ok || kid == "" {
+		return nil, errors.New("missing key id (kid) in token header")
+	}
+
+	privateKey, err := a.keyLookup.PrivateKey(kid)
+	if err != nil {
+		return nil, fmt.Errorf("private key: %w", err)
+	}
+
+	return &privateKey.PublicKey, nil
+}
+
+// =============================================================================
+
+type ctxKey int
+
+const claimKey ctxKey = 1
+
+// SetClaims stores the claims of an authenticated request in the context.
+func SetClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimKey, claims)
+}
+
+// GetClaims returns the claims stored in the context. The zero value, which
+// holds no roles, is returned for a request that wasn't authenticated.
+func GetClaims(ctx context.Context) Claims {
+	v, ok := ctx.Value(claimKey).(Claims)
+	if !ok {
+		return Claims{}
+	}
+	return v
+}
@@ -0,0 +1,137 @@
+package auth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/maxkulish/service-api/business/web/auth"
+	"github.com/maxkulish/service-api/foundation/keystore"
+)
+
+const (
+	kid    = "s4sKIjD9kIRjxs2tulPqGLdxSfgPErRN1Mu3Hd9k9NQ"
+	issuer = "service project"
+)
+
+func newAuth(t *testing.T, issuer string, key *rsa.PrivateKey) *auth.Auth {
+	t.Helper()
+
+	a, err := auth.New(auth.Config{
+		KeyLookup: keystore.NewMap(map[string]*rsa.PrivateKey{kid: key}),
+		ActiveKID: kid,
+		Issuer:    issuer,
+	})
+	if err != nil {
+		t.Fatalf("constructing auth: %v", err)
+	}
+
+	return a
+}
+
+func newKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func newClaims(ttl time.Duration, roles ...string) auth.Claims {
+	now := time.Now().UTC()
+
+	return auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "5cf37266-3473-4006-984f-9325122678b7",
+			IssuedAt:  jwt.NewNumericDate(now.Add(-time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Roles: roles,
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	key := newKey(t)
+	a := newAuth(t, issuer, key)
+
+	token, err := a.GenerateToken(newClaims(time.Hour, auth.RoleAdmin))
+	if err != nil {
+		t.Fatalf("generating token: %v", err)
+	}
+
+	claims, err := a.Authenticate("Bearer " + token)
+	if err != nil {
+		t.Fatalf("authenticating a valid token: %v", err)
+	}
+
+	if claims.Subject != "5cf37266-3473-4006-984f-9325122678b7" || !claims.HasRole(auth.RoleAdmin) {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+
+	if err := a.Authorize(claims, auth.RoleAdmin); err != nil {
+		t.Errorf("admin not authorized as admin: %v", err)
+	}
+
+	user, err := a.GenerateToken(newClaims(time.Hour, auth.RoleUser))
+	if err != nil {
+		t.Fatalf("generating token: %v", err)
+	}
+
+	claims, err = a.Authenticate("Bearer " + user)
+	if err != nil {
+		t.Fatalf("authenticating a valid token: %v", err)
+	}
+
+	if err := a.Authorize(claims, auth.RoleAdmin); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("user authorized as admin: %v", err)
+	}
+}
+
+func TestAuthenticateRejects(t *testing.T) {
+	key := newKey(t)
+	a := newAuth(t, issuer, key)
+
+	generate := func(a *auth.Auth, claims auth.Claims) string {
+		token, err := a.GenerateToken(claims)
+		if err != nil {
+			t.Fatalf("generating token: %v", err)
+		}
+		return token
+	}
+
+	valid := generate(a, newClaims(time.Hour))
+
+	noSubject := newClaims(time.Hour)
+	noSubject.Subject = ""
+
+	hmac, err := jwt.NewWithClaims(jwt.SigningMethodHS256, newClaims(time.Hour)).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"no header":      "",
+		"no scheme":      valid,
+		"basic scheme":   "Basic " + valid,
+		"malformed":      "Bearer not.a.token",
+		"expired":        "Bearer " + generate(a, newClaims(-time.Minute)),
+		"other issuer":   "Bearer " + generate(newAuth(t, "someone else", key), newClaims(time.Hour)),
+		"other key":      "Bearer " + generate(newAuth(t, issuer, newKey(t)), newClaims(time.Hour)),
+		"no subject":     "Bearer " + generate(a, noSubject),
+		"hmac signature": "Bearer " + hmac,
+	}
+
+	for name, header := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := a.Authenticate(header); err == nil {
+				t.Fatal("token accepted")
+			}
+		})
+	}
+}
@@ -23,3 +23,19 @@ func StandardLibraryMux() *http.ServeMux {
 
 	return mux
 }
+
+// RequireClientCert only lets requests through that were made over TLS with a
+// client certificate. The certificate is verified during the handshake, so
+// the server has to be configured with a client CA.
+func RequireClientCert(h http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			http.Error(w, "client certificate required", http.StatusForbidden)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	}
+
+	return http.HandlerFunc(f)
+}
@@ -0,0 +1,108 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/maxkulish/service-api/business/core/user"
+	"github.com/maxkulish/service-api/business/web/auth"
+	v1 "github.com/maxkulish/service-api/business/web/v1"
+	"github.com/maxkulish/service-api/foundation/web"
+)
+
+// Authenticate validates the bearer token of the request and stores its
+// claims in the context for the handlers and the Authorize middleware.
+func Authenticate(a *auth.Auth) web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			claims, err := a.Authenticate(r.Header.Get("Authorization"))
+			if err != nil {
+				return v1.NewRequestError(err, http.StatusUnauthorized)
+			}
+
+			ctx = auth.SetClaims(ctx, claims)
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// Enabled lets a request through when the user the token was issued to
+// still exists and is enabled. Without it a disabled user could keep using
+// a token until it expires. It must run after Authenticate.
+func Enabled(core *user.Core) web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			userID, err := uuid.Parse(auth.GetClaims(ctx).Subject)
+			if err != nil {
+				return v1.NewRequestError(errors.New("token subject is not a user id"), http.StatusUnauthorized)
+			}
+
+			usr, err := core.QueryByID(ctx, userID)
+			if err != nil {
+				if errors.Is(err, user.ErrNotFound) {
+					return v1.NewRequestError(errors.New("user no longer exists"), http.StatusUnauthorized)
+				}
+				return fmt.Errorf("querybyid: userID[%s]: %w", userID, err)
+			}
+
+			if !usr.Enabled {
+				return v1.NewRequestError(errors.New("user is disabled"), http.StatusUnauthorized)
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// Authorize lets a request through when its claims hold one of the roles.
+// It must run after Authenticate.
+func Authorize(a *auth.Auth, roles ...string) web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if err := a.Authorize(auth.GetClaims(ctx), roles...); err != nil {
+				return v1.NewRequestError(err, http.StatusForbidden)
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// AuthorizeSelf lets a request through when it is made by the user named by
+// the route parameter, or when its claims hold one of the roles. It must run
+// after Authenticate.
+func AuthorizeSelf(a *auth.Auth, param string, roles ...string) web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			claims := auth.GetClaims(ctx)
+
+			if claims.Subject == "" || claims.Subject != web.Param(r, param) {
+				if err := a.Authorize(claims, roles...); err != nil {
+					return v1.NewRequestError(err, http.StatusForbidden)
+				}
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,243 @@
+package mid_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dimfeld/httptreemux/v5"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/maxkulish/service-api/business/core/user"
+	"github.com/maxkulish/service-api/business/core/user/stores/usermem"
+	"github.com/maxkulish/service-api/business/web/auth"
+	v1 "github.com/maxkulish/service-api/business/web/v1"
+	"github.com/maxkulish/service-api/business/web/v1/mid"
+	"github.com/maxkulish/service-api/foundation/keystore"
+	"github.com/maxkulish/service-api/foundation/web"
+)
+
+const kid = "s4sKIjD9kIRjxs2tulPqGLdxSfgPErRN1Mu3Hd9k9NQ"
+
+func newAuth(t *testing.T) *auth.Auth {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := auth.New(auth.Config{
+		KeyLookup: keystore.NewMap(map[string]*rsa.PrivateKey{kid: key}),
+		ActiveKID: kid,
+		Issuer:    "service project",
+	})
+	if err != nil {
+		t.Fatalf("constructing auth: %s", err)
+	}
+
+	return a
+}
+
+func newClaims(subject string, roles ...string) auth.Claims {
+	return auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Roles: roles,
+	}
+}
+
+// run passes a request through the middleware to a handler that records
+// the claims it was called with. It returns the error of the chain and
+// whether the handler was reached.
+func run(ctx context.Context, m web.Middleware, r *http.Request) (auth.Claims, bool, error) {
+	var claims auth.Claims
+	var called bool
+
+	h := m(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		claims = auth.GetClaims(ctx)
+		called = true
+		return nil
+	})
+
+	err := h(ctx, httptest.NewRecorder(), r.WithContext(ctx))
+
+	return claims, called, err
+}
+
+// status returns the status the error is answered with.
+func status(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	if re := v1.GetRequestError(err); re != nil {
+		return re.Status
+	}
+	return http.StatusInternalServerError
+}
+
+func TestAuthenticate(t *testing.T) {
+	a := newAuth(t)
+
+	token, err := a.GenerateToken(newClaims("5cf37266-3473-4006-984f-9325122678b7", auth.RoleAdmin))
+	if err != nil {
+		t.Fatalf("generating token: %s", err)
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{name: "valid", header: "Bearer " + token, status: http.StatusOK},
+		{name: "missing", header: "", status: http.StatusUnauthorized},
+		{name: "no scheme", header: token, status: http.StatusUnauthorized},
+		{name: "bad token", header: "Bearer " + token[:len(token)-4], status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+
+			claims, called, err := run(context.Background(), mid.Authenticate(a), r)
+			if got := status(err); got != tt.status {
+				t.Fatalf("got status %d, want %d: %v", got, tt.status, err)
+			}
+			if called != (tt.status == http.StatusOK) {
+				t.Fatalf("handler called: %t", called)
+			}
+			if called && !claims.HasRole(auth.RoleAdmin) {
+				t.Fatalf("handler got claims %+v", claims)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	a := newAuth(t)
+
+	tests := []struct {
+		name   string
+		roles  []string
+		status int
+	}{
+		{name: "admin", roles: []string{auth.RoleAdmin}, status: http.StatusOK},
+		{name: "user", roles: []string{auth.RoleUser}, status: http.StatusForbidden},
+		{name: "no roles", status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := auth.SetClaims(context.Background(), newClaims("5cf37266-3473-4006-984f-9325122678b7", tt.roles...))
+
+			_, called, err := run(ctx, mid.Authorize(a, auth.RoleAdmin), httptest.NewRequest(http.MethodGet, "/", nil))
+			if got := status(err); got != tt.status {
+				t.Fatalf("got status %d, want %d: %v", got, tt.status, err)
+			}
+			if called != (tt.status == http.StatusOK) {
+				t.Fatalf("handler called: %t", called)
+			}
+		})
+	}
+
+	t.Run("no claims", func(t *testing.T) {
+		_, _, err := run(context.Background(), mid.Authorize(a, auth.RoleAdmin), httptest.NewRequest(http.MethodGet, "/", nil))
+		if got := status(err); got != http.StatusForbidden {
+			t.Fatalf("got status %d, want %d: %v", got, http.StatusForbidden, err)
+		}
+	})
+}
+
+func TestAuthorizeSelf(t *testing.T) {
+	a := newAuth(t)
+
+	const self = "45b5fbd3-755f-4379-8f07-a58d4a30fa2f"
+	const other = "5cf37266-3473-4006-984f-9325122678b7"
+
+	tests := []struct {
+		name    string
+		subject string
+		roles   []string
+		param   string
+		status  int
+	}{
+		{name: "self", subject: self, roles: []string{auth.RoleUser}, param: self, status: http.StatusOK},
+		{name: "other", subject: self, roles: []string{auth.RoleUser}, param: other, status: http.StatusForbidden},
+		{name: "admin other", subject: self, roles: []string{auth.RoleAdmin}, param: other, status: http.StatusOK},
+		{name: "no subject", roles: []string{auth.RoleUser}, param: "", status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := auth.SetClaims(context.Background(), newClaims(tt.subject, tt.roles...))
+			ctx = httptreemux.AddParamsToContext(ctx, map[string]string{"user_id": tt.param})
+
+			_, called, err := run(ctx, mid.AuthorizeSelf(a, "user_id", auth.RoleAdmin), httptest.NewRequest(http.MethodGet, "/", nil))
+			if got := status(err); got != tt.status {
+				t.Fatalf("got status %d, want %d: %v", got, tt.status, err)
+			}
+			if called != (tt.status == http.StatusOK) {
+				t.Fatalf("handler called: %t", called)
+			}
+		})
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	core := user.NewCore(usermem.NewStore())
+	ctx := context.Background()
+
+	newUser := func(email string) user.User {
+		usr, err := core.Create(ctx, user.NewUser{
+			Name:     "Gopher",
+			Email:    email,
+			Roles:    []string{auth.RoleUser},
+			Password: "gophers",
+		})
+		if err != nil {
+			t.Fatalf("create: %s", err)
+		}
+		return usr
+	}
+
+	enabled := newUser("enabled@example.com")
+	disabled := newUser("disabled@example.com")
+
+	off := false
+	if _, err := core.Update(ctx, disabled, user.UpdateUser{Enabled: &off}); err != nil {
+		t.Fatalf("update: %s", err)
+	}
+
+	tests := []struct {
+		name    string
+		subject string
+		status  int
+	}{
+		{name: "enabled", subject: enabled.ID.String(), status: http.StatusOK},
+		{name: "disabled", subject: disabled.ID.String(), status: http.StatusUnauthorized},
+		{name: "deleted", subject: "5cf37266-3473-4006-984f-9325122678b7", status: http.StatusUnauthorized},
+		{name: "not an id", subject: "admin", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := auth.SetClaims(ctx, newClaims(tt.subject))
+
+			_, called, err := run(ctx, mid.Enabled(core), httptest.NewRequest(http.MethodGet, "/", nil))
+			if got := status(err); got != tt.status {
+				t.Fatalf("got status %d, want %d: %v", got, tt.status, err)
+			}
+			if called != (tt.status == http.StatusOK) {
+				t.Fatalf("handler called: %t", called)
+			}
+		})
+	}
+}
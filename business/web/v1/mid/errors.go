@@ -0,0 +1,82 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	v1 "github.com/maxkulish/service-api/business/web/v1"
+	"github.com/maxkulish/service-api/foundation/logger"
+	"github.com/maxkulish/service-api/foundation/web"
+	"go.uber.org/zap"
+)
+
+// Errors handles errors coming out of the call chain. It detects normal
+// application errors which are used to respond to the client in a uniform way.
+// Any other error is reported as a 500 without exposing its details. The
+// cause of every error is logged.
+func Errors(log *zap.SugaredLogger) web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if err := handler(ctx, w, r); err != nil {
+				logger.WithTrace(ctx, log).Errorw("ERROR", "message", err)
+
+				// If the handler already wrote a response the client has it
+				// and a second body would only corrupt it.
+				if web.GetValues(ctx).StatusCode != 0 {
+					if web.IsShutdown(err) {
+						return err
+					}
+					return nil
+				}
+
+				var er v1.ErrorResponse
+				var status int
+
+				switch {
+				case v1.IsRequestError(err):
+					reqErr := v1.GetRequestError(err)
+					er = v1.ErrorResponse{
+						Error: reqErr.Error(),
+					}
+					if fieldErrors := v1.GetFieldErrors(reqErr.Err); fieldErrors != nil {
+						er = v1.ErrorResponse{
+							Error:  "data validation error",
+							Fields: fieldErrors.Fields(),
+						}
+					}
+					status = reqErr.Status
+
+				case v1.IsFieldErrors(err):
+					fieldErrors := v1.GetFieldErrors(err)
+					er = v1.ErrorResponse{
+						Error:  "data validation error",
+						Fields: fieldErrors.Fields(),
+					}
+					status = http.StatusBadRequest
+
+				default:
+					er = v1.ErrorResponse{
+						Error: http.StatusText(http.StatusInternalServerError),
+					}
+					status = http.StatusInternalServerError
+				}
+
+				if err := web.Respond(ctx, w, er, status); err != nil {
+					return err
+				}
+
+				// If we receive the shutdown err we need to return it
+				// back to the base handler to shut down the service.
+				if web.IsShutdown(err) {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,151 @@
+package mid_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "github.com/maxkulish/service-api/business/web/v1"
+	"github.com/maxkulish/service-api/business/web/v1/mid"
+	"github.com/maxkulish/service-api/foundation/web"
+	"go.uber.org/zap"
+)
+
+func TestErrors(t *testing.T) {
+	fields := v1.FieldErrors{
+		{Field: "name", Err: "is required"},
+		{Field: "email", Err: "must be a valid email address"},
+	}
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		resp   v1.ErrorResponse
+	}{
+		{
+			name:   "request error",
+			err:    v1.NewRequestError(errors.New("user not found"), http.StatusNotFound),
+			status: http.StatusNotFound,
+			resp:   v1.ErrorResponse{Error: "user not found"},
+		},
+		{
+			name:   "wrapped request error",
+			err:    fmt.Errorf("querybyid: %w", v1.NewRequestError(errors.New("conflict"), http.StatusConflict)),
+			status: http.StatusConflict,
+			resp:   v1.ErrorResponse{Error: "conflict"},
+		},
+		{
+			name:   "field errors",
+			err:    fields,
+			status: http.StatusBadRequest,
+			resp: v1.ErrorResponse{
+				Error:  "data validation error",
+				Fields: map[string]string{"name": "is required", "email": "must be a valid email address"},
+			},
+		},
+		{
+			name:   "request error with field errors",
+			err:    v1.NewRequestError(fields, http.StatusUnprocessableEntity),
+			status: http.StatusUnprocessableEntity,
+			resp: v1.ErrorResponse{
+				Error:  "data validation error",
+				Fields: map[string]string{"name": "is required", "email": "must be a valid email address"},
+			},
+		},
+		{
+			name:   "unknown error",
+			err:    errors.New("pq: password authentication failed for user postgres"),
+			status: http.StatusInternalServerError,
+			resp:   v1.ErrorResponse{Error: "Internal Server Error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := web.NewApp(make(chan os.Signal, 1), mid.Errors(zap.NewNop().Sugar()))
+			app.Handle(http.MethodGet, "", "/test", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return tt.err
+			})
+
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("got status %d, want %d", w.Code, tt.status)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Fatalf("got content type %q", ct)
+			}
+
+			var resp v1.ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decoding %s: %s", w.Body, err)
+			}
+			if !reflect.DeepEqual(resp, tt.resp) {
+				t.Fatalf("got %+v, want %+v", resp, tt.resp)
+			}
+
+			if tt.status == http.StatusInternalServerError && strings.Contains(w.Body.String(), "password") {
+				t.Fatalf("error leaked to the client: %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestErrorsAfterResponse(t *testing.T) {
+	app := web.NewApp(make(chan os.Signal, 1), mid.Errors(zap.NewNop().Sugar()))
+	app.Handle(http.MethodGet, "", "/test", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if err := web.Respond(ctx, w, "created", http.StatusCreated); err != nil {
+			return err
+		}
+		return errors.New("failed after responding")
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	if w.Code != http.StatusCreated || w.Body.String() != `"created"` {
+		t.Fatalf("got status %d with body %s, want the first response only", w.Code, w.Body)
+	}
+}
+
+func TestErrorsShutdown(t *testing.T) {
+	shutdownErr := web.NewShutdownError("data corrupted")
+
+	h := mid.Errors(zap.NewNop().Sugar())(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return shutdownErr
+	})
+
+	// The error is answered and still passed up so the App signals the
+	// shutdown.
+	w := httptest.NewRecorder()
+	got := h(context.Background(), w, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	if !errors.Is(got, shutdownErr) {
+		t.Fatalf("got %v, want the shutdown error", got)
+	}
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "corrupted") {
+		t.Fatalf("got status %d with body %s", w.Code, w.Body)
+	}
+
+	shutdown := make(chan os.Signal, 1)
+	app := web.NewApp(shutdown, mid.Errors(zap.NewNop().Sugar()))
+	app.Handle(http.MethodGet, "", "/test", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return shutdownErr
+	})
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	select {
+	case <-shutdown:
+	default:
+		t.Fatal("shutdown error didn't reach the App")
+	}
+}
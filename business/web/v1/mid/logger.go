@@ -0,0 +1,43 @@
+// Package mid contains the set of middleware functions.
+package mid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/maxkulish/service-api/foundation/logger"
+	"github.com/maxkulish/service-api/foundation/web"
+	"go.uber.org/zap"
+)
+
+// Logger writes information about the request to the logs.
+func Logger(log *zap.SugaredLogger) web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			v := web.GetValues(ctx)
+			log := logger.WithTrace(ctx, log)
+
+			path := r.URL.Path
+			if r.URL.RawQuery != "" {
+				path = fmt.Sprintf("%s?%s", path, r.URL.RawQuery)
+			}
+
+			log.Infow("request started", "method", r.Method, "path", path,
+				"remoteaddr", r.RemoteAddr)
+
+			err := handler(ctx, w, r)
+
+			log.Infow("request completed", "method", r.Method, "path", path,
+				"remoteaddr", r.RemoteAddr, "statuscode", v.StatusCode, "bytes", v.BytesWritten,
+				"latency", time.Since(v.Now))
+
+			return err
+		}
+
+		return h
+	}
+
+	return m
+}
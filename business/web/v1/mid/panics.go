@@ -0,0 +1,40 @@
+package mid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/maxkulish/service-api/business/web/v1/metrics"
+	"github.com/maxkulish/service-api/foundation/web"
+)
+
+// Panics recovers from panics and converts the panic to an error so it is
+// reported in Metrics and handled in Errors.
+func Panics() web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+
+		// Use the named return error so the deferred function can
+		// replace it when a panic is recovered.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) (err error) {
+
+			// Defer a function to recover from a panic and set the err return
+			// variable after the fact.
+			defer func() {
+				if rec := recover(); rec != nil {
+					trace := debug.Stack()
+					err = fmt.Errorf("PANIC [%v] TRACE[%s]", rec, string(trace))
+
+					metrics.AddPanics(ctx)
+				}
+			}()
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
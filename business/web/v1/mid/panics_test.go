@@ -0,0 +1,106 @@
+package mid_test
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/maxkulish/service-api/business/web/v1/mid"
+	"github.com/maxkulish/service-api/foundation/web"
+	"go.uber.org/zap"
+)
+
+// counter returns the value of one of the expvar metrics.
+func counter(t *testing.T, name string) int64 {
+	t.Helper()
+
+	v, ok := expvar.Get(name).(*expvar.Int)
+	if !ok {
+		t.Fatalf("metric %s not registered", name)
+	}
+	return v.Value()
+}
+
+func TestPanics(t *testing.T) {
+	var err error
+	h := mid.Panics()(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		panic("nil map")
+	})
+
+	func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				t.Fatalf("panic escaped the middleware: %v", rec)
+			}
+		}()
+		err = h(context.Background(), httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+	}()
+
+	if err == nil || !strings.Contains(err.Error(), "PANIC [nil map]") {
+		t.Fatalf("got %v, want the panic as an error", err)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	shutdown := make(chan os.Signal, 1)
+	app := web.NewApp(shutdown, mid.Errors(zap.NewNop().Sugar()), mid.Metrics(), mid.Panics())
+
+	app.Handle(http.MethodGet, "", "/ok", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, "ok", http.StatusOK)
+	})
+	app.Handle(http.MethodGet, "", "/error", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return errors.New("failed")
+	})
+	app.Handle(http.MethodGet, "", "/panic", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		panic("nil map")
+	})
+
+	tests := []struct {
+		path     string
+		status   int
+		requests int64
+		errors   int64
+		panics   int64
+	}{
+		{path: "/ok", status: http.StatusOK, requests: 1},
+		{path: "/error", status: http.StatusInternalServerError, requests: 1, errors: 1},
+		{path: "/panic", status: http.StatusInternalServerError, requests: 1, errors: 1, panics: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			requests, errs, panics := counter(t, "requests"), counter(t, "errors"), counter(t, "panics")
+
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("got status %d, want %d", w.Code, tt.status)
+			}
+			if strings.Contains(w.Body.String(), "nil map") {
+				t.Fatalf("panic leaked to the client: %s", w.Body)
+			}
+
+			if d := counter(t, "requests") - requests; d != tt.requests {
+				t.Errorf("requests went up by %d, want %d", d, tt.requests)
+			}
+			if d := counter(t, "errors") - errs; d != tt.errors {
+				t.Errorf("errors went up by %d, want %d", d, tt.errors)
+			}
+			if d := counter(t, "panics") - panics; d != tt.panics {
+				t.Errorf("panics went up by %d, want %d", d, tt.panics)
+			}
+		})
+	}
+
+	select {
+	case <-shutdown:
+		t.Fatal("a panic signalled a shutdown")
+	default:
+	}
+}
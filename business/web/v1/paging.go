@@ -0,0 +1,63 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Default values used when a request doesn't ask for a page.
+const (
+	defaultPage        = 1
+	defaultRowsPerPage = 10
+	maxRowsPerPage     = 100
+)
+
+// PageDocument is the form used for API responses that return a page of a
+// larger collection.
+type PageDocument[T any] struct {
+	Items       []T `json:"items"`
+	Total       int `json:"total"`
+	Page        int `json:"page"`
+	RowsPerPage int `json:"rowsPerPage"`
+}
+
+// NewPageDocument constructs a response value for a page of items.
+func NewPageDocument[T any](items []T, total int, page int, rowsPerPage int) PageDocument[T] {
+	if items == nil {
+		items = []T{}
+	}
+
+	return PageDocument[T]{
+		Items:       items,
+		Total:       total,
+		Page:        page,
+		RowsPerPage: rowsPerPage,
+	}
+}
+
+// ParsePage reads the page and rows query parameters of a request. A
+// parameter that isn't set gets its default value.
+func ParsePage(r *http.Request) (page int, rowsPerPage int, err error) {
+	values := r.URL.Query()
+
+	page, err = parseInt(values.Get("page"), defaultPage)
+	if err != nil || page < 1 {
+		return 0, 0, NewFieldsError("page", errors.New("must be a positive number"))
+	}
+
+	rowsPerPage, err = parseInt(values.Get("rows"), defaultRowsPerPage)
+	if err != nil || rowsPerPage < 1 || rowsPerPage > maxRowsPerPage {
+		return 0, 0, NewFieldsError("rows", fmt.Errorf("must be a number between 1 and %d", maxRowsPerPage))
+	}
+
+	return page, rowsPerPage, nil
+}
+
+func parseInt(s string, def int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	return strconv.Atoi(s)
+}
@@ -0,0 +1,202 @@
+// Package certs provides support for serving TLS, and optionally mutual TLS,
+// with certificates that are reloaded from disk when they are rotated.
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// checkInterval is how often the files are checked for changes. The check
+// happens during a handshake so an idle server doesn't touch the disk.
+const checkInterval = 5 * time.Second
+
+// Reloader holds the server certificate and the optional client CA pool read
+// from disk. The files are read again when their modification time changes.
+// If the new files can't be loaded, for example because the certificate was
+// replaced before its key, the current ones stay in use until the next check.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	pool     *x509.CertPool
+	modTimes []time.Time
+	checked  time.Time
+}
+
+// NewReloader constructs a Reloader and loads the files. The client CA file
+// is optional and only needed to verify client certificates.
+func NewReloader(certFile string, keyFile string, caFile string) (*Reloader, error) {
+	r := Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+	}
+
+	modTimes, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.load(modTimes); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// ServerConfig returns a TLS configuration that serves the current
+// certificate. When clientAuth is true, clients must present a certificate
+// signed by the client CA. Otherwise a certificate is only verified if the
+// client presents one and a client CA was provided.
+func (r *Reloader) ServerConfig(clientAuth bool) (*tls.Config, error) {
+	if clientAuth && r.caFile == "" {
+		return nil, errors.New("requiring client certificates needs a client CA")
+	}
+
+	cfg := tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.getCertificate,
+	}
+
+	// The client certificates are verified by hand against the current pool
+	// since the ClientCAs field can't change once the server is running.
+	switch {
+	case clientAuth:
+		cfg.ClientAuth = tls.RequireAnyClientCert
+		cfg.VerifyPeerCertificate = r.verifyClient
+
+	case r.caFile != "":
+		cfg.ClientAuth = tls.RequestClientCert
+		cfg.VerifyPeerCertificate = r.verifyClient
+	}
+
+	return &cfg, nil
+}
+
+func (r *Reloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.check()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+// verifyClient verifies the chain presented by the client against the
+// current client CA pool. A client that presents no certificate is accepted,
+// the ClientAuth setting decides if that is allowed.
+func (r *Reloader) verifyClient(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return nil
+	}
+
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("parsing client certificate: %w", err)
+		}
+		certs[i] = cert
+	}
+
+	r.mu.RLock()
+	pool := r.pool
+	r.mu.RUnlock()
+
+	opts := x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	if _, err := certs[0].Verify(opts); err != nil {
+		return fmt.Errorf("verifying client certificate: %w", err)
+	}
+
+	return nil
+}
+
+// check reloads the files if they changed since the last check. Only one
+// handshake every checkInterval pays for looking at the files.
+func (r *Reloader) check() {
+	r.mu.Lock()
+	if time.Since(r.checked) < checkInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.checked = time.Now()
+	current := r.modTimes
+	r.mu.Unlock()
+
+	modTimes, err := r.stat()
+	if err != nil {
+		return
+	}
+
+	for i := range modTimes {
+		if !modTimes[i].Equal(current[i]) {
+			r.load(modTimes)
+			return
+		}
+	}
+}
+
+func (r *Reloader) load(modTimes []time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading key pair: %w", err)
+	}
+
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("reading client CA: %w", err)
+		}
+
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in client CA %s", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cert = &cert
+	r.pool = pool
+	r.modTimes = modTimes
+	r.checked = time.Now()
+
+	return nil
+}
+
+func (r *Reloader) stat() ([]time.Time, error) {
+	files := []string{r.certFile, r.keyFile}
+	if r.caFile != "" {
+		files = append(files, r.caFile)
+	}
+
+	modTimes := make([]time.Time, len(files))
+	for i, file := range files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[i] = fi.ModTime()
+	}
+
+	return modTimes, nil
+}
@@ -0,0 +1,330 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// authority issues the certificates used by the tests.
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newAuthority(t *testing.T) authority {
+	t.Helper()
+
+	key := newKey(t)
+
+	tmpl := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return authority{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue returns a leaf certificate and its key in PEM form.
+func (a authority) issue(t *testing.T, name string, usage x509.ExtKeyUsage) (certPEM []byte, keyPEM []byte) {
+	t.Helper()
+
+	key := newKey(t)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return certPEM, keyPEM
+}
+
+func (a authority) clientCert(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	certPEM, keyPEM := a.issue(t, "client", x509.ExtKeyUsageClientAuth)
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+// files holds the paths of the server certificate, its key and the client CA.
+type files struct {
+	cert string
+	key  string
+	ca   string
+}
+
+func writeFiles(t *testing.T, ca authority, name string) files {
+	t.Helper()
+
+	dir := t.TempDir()
+	f := files{
+		cert: filepath.Join(dir, "tls.crt"),
+		key:  filepath.Join(dir, "tls.key"),
+		ca:   filepath.Join(dir, "ca.crt"),
+	}
+
+	f.rotate(t, ca, name)
+	write(t, f.ca, ca.pem)
+
+	return f
+}
+
+func (f files) rotate(t *testing.T, ca authority, name string) {
+	t.Helper()
+
+	certPEM, keyPEM := ca.issue(t, name, x509.ExtKeyUsageServerAuth)
+	write(t, f.key, keyPEM)
+	write(t, f.cert, certPEM)
+}
+
+func write(t *testing.T, name string, data []byte) {
+	t.Helper()
+
+	if err := os.WriteFile(name, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serve starts a server with the configuration and returns its address.
+func serve(t *testing.T, cfg *tls.Config) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}),
+		ReadHeaderTimeout: time.Second,
+	}
+	go srv.Serve(tls.NewListener(ln, cfg))
+	t.Cleanup(func() { srv.Close() })
+
+	return ln.Addr().String()
+}
+
+func get(ca authority, addr string, certs ...tls.Certificate) error {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	client := http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs:      roots,
+				Certificates: certs,
+			},
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Get("https://" + addr)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+func TestServerConfigRequiresClientCert(t *testing.T) {
+	ca := newAuthority(t)
+	f := writeFiles(t, ca, "server")
+
+	r, err := NewReloader(f.cert, f.key, f.ca)
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+
+	cfg, err := r.ServerConfig(true)
+	if err != nil {
+		t.Fatalf("ServerConfig: %v", err)
+	}
+	addr := serve(t, cfg)
+
+	if err := get(ca, addr); err == nil {
+		t.Error("client without a certificate accepted")
+	}
+
+	other := newAuthority(t)
+	if err := get(ca, addr, other.clientCert(t)); err == nil {
+		t.Error("client with a certificate from another CA accepted")
+	}
+
+	if err := get(ca, addr, ca.clientCert(t)); err != nil {
+		t.Errorf("client with a certificate signed by the CA rejected: %v", err)
+	}
+}
+
+func TestServerConfigVerifiesClientCertIfGiven(t *testing.T) {
+	ca := newAuthority(t)
+	f := writeFiles(t, ca, "server")
+
+	r, err := NewReloader(f.cert, f.key, f.ca)
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+
+	cfg, err := r.ServerConfig(false)
+	if err != nil {
+		t.Fatalf("ServerConfig: %v", err)
+	}
+	addr := serve(t, cfg)
+
+	if err := get(ca, addr); err != nil {
+		t.Errorf("client without a certificate rejected: %v", err)
+	}
+
+	other := newAuthority(t)
+	if err := get(ca, addr, other.clientCert(t)); err == nil {
+		t.Error("client with a certificate from another CA accepted")
+	}
+
+	if err := get(ca, addr, ca.clientCert(t)); err != nil {
+		t.Errorf("client with a certificate signed by the CA rejected: %v", err)
+	}
+}
+
+func TestServerConfigRequiresClientCA(t *testing.T) {
+	ca := newAuthority(t)
+	f := writeFiles(t, ca, "server")
+
+	r, err := NewReloader(f.cert, f.key, "")
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+
+	if _, err := r.ServerConfig(true); err == nil {
+		t.Fatal("requiring client certificates without a client CA allowed")
+	}
+}
+
+func TestReloaderPicksUpRotatedCert(t *testing.T) {
+	ca := newAuthority(t)
+	f := writeFiles(t, ca, "first")
+
+	r, err := NewReloader(f.cert, f.key, "")
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+
+	cfg, err := r.ServerConfig(false)
+	if err != nil {
+		t.Fatalf("ServerConfig: %v", err)
+	}
+	addr := serve(t, cfg)
+
+	f.rotate(t, ca, "second")
+
+	// The modification time has to change for the files to be read again,
+	// which a fast rewrite doesn't guarantee on every file system.
+	later := time.Now().Add(time.Minute)
+	for _, name := range []string{f.cert, f.key} {
+		if err := os.Chtimes(name, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := serverName(t, ca, addr); got != "first" {
+		t.Fatalf("files read again before checkInterval passed: got %q", got)
+	}
+
+	// Moving the last check back stands in for waiting out checkInterval.
+	r.mu.Lock()
+	r.checked = time.Now().Add(-checkInterval)
+	r.mu.Unlock()
+
+	if got := serverName(t, ca, addr); got != "second" {
+		t.Fatalf("rotated certificate not served: got %q", got)
+	}
+}
+
+// serverName returns the common name of the certificate the server presents.
+func serverName(t *testing.T, ca authority, addr string) string {
+	t.Helper()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: roots})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
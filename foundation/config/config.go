@@ -0,0 +1,152 @@
+// Package config provides support for reading configuration from a YAML or
+// JSON file that is layered underneath environment variables and flags.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/ardanlabs/conf/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// Parse parses the configuration into cfg the same as conf.Parse, with the
+// config file applied after the defaults and before the environment
+// variables and flags.
+func Parse(prefix string, cfg any, f File) (string, error) {
+	help, err := conf.Parse(prefix, cfg, f)
+	if err != nil {
+		return help, err
+	}
+
+	// conf sets the default of every field still holding its zero value
+	// after the file was read, including the fields the file set to zero.
+	// Applying the file again puts those back, except for the fields an
+	// environment variable or flag set since they take precedence.
+	doc, err := f.read()
+	if err != nil || doc == nil {
+		return "", err
+	}
+
+	src := newSources(prefix, os.Args[1:])
+	if err := restore(doc, reflect.ValueOf(cfg).Elem(), nil, src); err != nil {
+		return "", fmt.Errorf("applying config file %s: %w", f.path, err)
+	}
+
+	return "", nil
+}
+
+// File implements the conf.Parsers interface to read a config file. Use it
+// through Parse, since conf on its own replaces a value the file set to the
+// zero value of its type with the default.
+type File struct {
+	path    string
+	partial bool
+}
+
+// WithFile constructs a File for the specified path. An empty path means no
+// config file is used.
+func WithFile(path string) File {
+	return File{
+		path: path,
+	}
+}
+
+// Partial returns a File that ignores the keys that don't match a field. It
+// is meant for tools that read a config file written for another program and
+// only need some of its settings.
+func (f File) Partial() File {
+	f.partial = true
+	return f
+}
+
+// Process reads the config file into the config struct. Keys match the names
+// of the struct fields ignoring case, dashes and underscores, so read_timeout,
+// read-timeout and readTimeout all set ReadTimeout. A key that doesn't match
+// a field is rejected unless the File is partial.
+func (f File) Process(prefix string, cfg any) error {
+	doc, err := f.read()
+	if err != nil || doc == nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("normalizing config file %s: %w", f.path, err)
+	}
+
+	d := yaml.NewDecoder(bytes.NewReader(data))
+	d.KnownFields(!f.partial)
+
+	if err := d.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("decoding config file %s: %w", f.path, err)
+	}
+
+	return nil
+}
+
+// read parses the config file with its keys normalized. It returns nil when
+// no config file is used.
+func (f File) read() (*yaml.Node, error) {
+	if f.path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	// YAML is a superset of JSON so a single decoder handles both.
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", f.path, err)
+	}
+	normalizeKeys(&doc)
+
+	return &doc, nil
+}
+
+// Path returns the config file path provided by the --config-file flag or the
+// <prefix>_CONFIG_FILE environment variable. The flag takes precedence, the
+// same as it does for every other setting. The path has to be known before
+// the configuration is parsed so it is looked up directly.
+func Path(prefix string, args []string) string {
+	const flag = "--config-file"
+
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+
+		if v, ok := strings.CutPrefix(arg, flag+"="); ok {
+			return v
+		}
+
+		if arg == flag && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+
+	return os.Getenv(prefix + "_CONFIG_FILE")
+}
+
+// normalizeKeys rewrites every mapping key to the lower case form yaml uses
+// for struct fields without a yaml tag.
+func normalizeKeys(n *yaml.Node) {
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i < len(n.Content); i += 2 {
+			key := n.Content[i]
+			key.Value = strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(key.Value))
+		}
+	}
+
+	for _, c := range n.Content {
+		normalizeKeys(c)
+	}
+}
@@ -0,0 +1,97 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maxkulish/service-api/foundation/config"
+)
+
+type testConfig struct {
+	Web struct {
+		ReadTimeout time.Duration `conf:"default:5s"`
+		APIHost     string        `conf:"default:0.0.0.0:3000"`
+	}
+	DB struct {
+		DisableTLS   bool `conf:"default:true"`
+		MaxIdleConns int  `conf:"default:2"`
+	}
+	HTTP2 struct {
+		Cleartext bool `conf:"default:true,env:HTTP2_CLEARTEXT,flag:http2-cleartext"`
+	}
+}
+
+func parse(t *testing.T, file string, args ...string) testConfig {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	orig := os.Args
+	os.Args = append([]string{"test"}, args...)
+	t.Cleanup(func() { os.Args = orig })
+
+	var cfg testConfig
+	if _, err := config.Parse("TEST", &cfg, config.WithFile(path)); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	return cfg
+}
+
+func TestParseFileSetsZeroValues(t *testing.T) {
+	cfg := parse(t, `
+db:
+  disableTLS: false
+  max_idle_conns: 0
+http2:
+  cleartext: false
+web:
+  read-timeout: 0s
+`)
+
+	if cfg.DB.DisableTLS || cfg.DB.MaxIdleConns != 0 || cfg.HTTP2.Cleartext || cfg.Web.ReadTimeout != 0 {
+		t.Fatalf("zero values from the file replaced by defaults: %+v", cfg)
+	}
+
+	if cfg.Web.APIHost != "0.0.0.0:3000" {
+		t.Fatalf("default not applied to a key missing from the file: %q", cfg.Web.APIHost)
+	}
+}
+
+func TestParsePrecedence(t *testing.T) {
+	const file = `
+db:
+  disableTLS: false
+  maxIdleConns: 5
+http2:
+  cleartext: false
+web:
+  apiHost: 0.0.0.0:4000
+`
+
+	t.Setenv("TEST_DB_DISABLE_TLS", "true")
+	t.Setenv("TEST_HTTP2_CLEARTEXT", "true")
+
+	cfg := parse(t, file, "--db-max-idle-conns=0", "--http2-cleartext=false", "--web-api-host", "0.0.0.0:5000")
+
+	if !cfg.DB.DisableTLS {
+		t.Error("environment variable did not override the file")
+	}
+
+	if cfg.DB.MaxIdleConns != 0 {
+		t.Errorf("flag did not override the file: got %d", cfg.DB.MaxIdleConns)
+	}
+
+	if cfg.HTTP2.Cleartext {
+		t.Error("flag did not override the environment variable")
+	}
+
+	if cfg.Web.APIHost != "0.0.0.0:5000" {
+		t.Errorf("flag did not override the file: got %q", cfg.Web.APIHost)
+	}
+}
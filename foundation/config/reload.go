@@ -0,0 +1,156 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Reloader re-reads the configuration on demand and applies the changes made
+// to fields tagged reload:"true". A tag on a struct field marks every field
+// inside of it. Changes to any other field are refused with a warning since
+// the components using them can't pick up a new value without a restart.
+type Reloader[T any] struct {
+	log  *zap.SugaredLogger
+	base T
+	load func(cfg any) error
+
+	mu   sync.Mutex
+	cfg  *T
+	subs []func()
+}
+
+// NewReloader constructs a Reloader for the configuration held by cfg. The
+// base value is what the configuration is parsed into before any source is
+// applied, usually the value holding the version information. The load
+// function applies the configuration sources to the value it is given.
+func NewReloader[T any](log *zap.SugaredLogger, cfg *T, base T, load func(cfg any) error) *Reloader[T] {
+	return &Reloader[T]{
+		log:  log,
+		base: base,
+		load: load,
+		cfg:  cfg,
+	}
+}
+
+// Subscribe registers a function that is called every time a reload changes
+// the configuration. Subscribers read the new values from the configuration
+// value provided to NewReloader. They are called from the goroutine that
+// calls Reload, which is the only place that value changes.
+func (r *Reloader[T]) Subscribe(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.subs = append(r.subs, fn)
+}
+
+// Reload reads the configuration sources again and applies the changes to
+// the reloadable fields before notifying the subscribers.
+func (r *Reloader[T]) Reload() error {
+	next := r.base
+	if err := r.load(&next); err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	r.mu.Lock()
+	changed := r.apply(reflect.ValueOf(r.cfg).Elem(), reflect.ValueOf(&next).Elem(), "", false)
+	subs := r.subs
+	r.mu.Unlock()
+
+	r.log.Infow("config reload", "status", "reload complete", "changed", changed)
+
+	if changed == 0 {
+		return nil
+	}
+
+	for _, fn := range subs {
+		fn()
+	}
+
+	return nil
+}
+
+// Run reloads the configuration every time a signal is received on the
+// reload channel. When a config file is provided it is also reloaded every
+// time its modification time changes, checked at the specified interval.
+// Run blocks until the context is cancelled.
+func (r *Reloader[T]) Run(ctx context.Context, reload <-chan os.Signal, file string, interval time.Duration) {
+	var tick <-chan time.Time
+	var modTime time.Time
+
+	if file != "" && interval > 0 {
+		if fi, err := os.Stat(file); err == nil {
+			modTime = fi.ModTime()
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case sig := <-reload:
+			r.log.Infow("config reload", "status", "reload started", "signal", sig)
+
+		case <-tick:
+			fi, err := os.Stat(file)
+			if err != nil || fi.ModTime().Equal(modTime) {
+				continue
+			}
+			modTime = fi.ModTime()
+
+			r.log.Infow("config reload", "status", "reload started", "file", file)
+
+		case <-ctx.Done():
+			return
+		}
+
+		if err := r.Reload(); err != nil {
+			r.log.Errorw("config reload", "status", "reload failed", "ERROR", err)
+		}
+	}
+}
+
+// apply copies the changed reloadable fields from next into cur and returns
+// the number of fields it changed. Values are not logged since they may be
+// secrets.
+func (r *Reloader[T]) apply(cur reflect.Value, next reflect.Value, path string, reloadable bool) int {
+	var changed int
+
+	for i := 0; i < cur.NumField(); i++ {
+		fld := cur.Type().Field(i)
+		if !fld.IsExported() {
+			continue
+		}
+
+		name := path + fld.Name
+		canReload := reloadable || fld.Tag.Get("reload") == "true"
+
+		if fld.Type.Kind() == reflect.Struct && fld.Type != reflect.TypeOf(time.Time{}) {
+			changed += r.apply(cur.Field(i), next.Field(i), name+".", canReload)
+			continue
+		}
+
+		if reflect.DeepEqual(cur.Field(i).Interface(), next.Field(i).Interface()) {
+			continue
+		}
+
+		if !canReload {
+			r.log.Warnw("config reload", "status", "change refused, field is not reloadable", "field", name)
+			continue
+		}
+
+		cur.Field(i).Set(next.Field(i))
+		changed++
+
+		r.log.Infow("config reload", "status", "field changed", "field", name)
+	}
+
+	return changed
+}
@@ -0,0 +1,166 @@
+package config_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/maxkulish/service-api/foundation/config"
+	"go.uber.org/zap"
+)
+
+type reloadConfig struct {
+	Log struct {
+		Level string `conf:"default:info" reload:"true"`
+	}
+	Web struct {
+		ReadTimeout time.Duration `conf:"default:5s" reload:"true"`
+		APIHost     string        `conf:"default:0.0.0.0:3000"`
+	}
+	Limits struct {
+		Burst int `conf:"default:10"`
+		Rate  int `conf:"default:5"`
+	} `reload:"true"`
+}
+
+func TestReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(file string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	orig := os.Args
+	os.Args = []string{"test"}
+	t.Cleanup(func() { os.Args = orig })
+
+	load := func(cfg any) error {
+		_, err := config.Parse("TEST", cfg, config.WithFile(path))
+		return err
+	}
+
+	write(`
+web:
+  apiHost: 0.0.0.0:3000
+`)
+
+	var cfg reloadConfig
+	if err := load(&cfg); err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+
+	r := config.NewReloader(zap.NewNop().Sugar(), &cfg, reloadConfig{}, load)
+
+	var calls int
+	r.Subscribe(func() { calls++ })
+
+	t.Run("unchanged", func(t *testing.T) {
+		if err := r.Reload(); err != nil {
+			t.Fatalf("reload: %s", err)
+		}
+		if calls != 0 {
+			t.Fatalf("subscribers called %d times without a change", calls)
+		}
+	})
+
+	t.Run("not reloadable", func(t *testing.T) {
+		write(`
+web:
+  apiHost: 0.0.0.0:4000
+`)
+
+		if err := r.Reload(); err != nil {
+			t.Fatalf("reload: %s", err)
+		}
+		if cfg.Web.APIHost != "0.0.0.0:3000" {
+			t.Fatalf("got api host %q, want the change refused", cfg.Web.APIHost)
+		}
+		if calls != 0 {
+			t.Fatalf("subscribers called %d times for a refused change", calls)
+		}
+	})
+
+	t.Run("reloadable", func(t *testing.T) {
+		write(`
+log:
+  level: debug
+web:
+  apiHost: 0.0.0.0:4000
+  readTimeout: 0s
+limits:
+  burst: 20
+`)
+
+		if err := r.Reload(); err != nil {
+			t.Fatalf("reload: %s", err)
+		}
+		if cfg.Log.Level != "debug" || cfg.Web.ReadTimeout != 0 || cfg.Limits.Burst != 20 || cfg.Limits.Rate != 5 {
+			t.Fatalf("reloadable changes not applied: %+v", cfg)
+		}
+		if cfg.Web.APIHost != "0.0.0.0:3000" {
+			t.Fatalf("got api host %q, want the change refused", cfg.Web.APIHost)
+		}
+		if calls != 1 {
+			t.Fatalf("subscribers called %d times, want once", calls)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		write(`
+log:
+  level: [not, a, string]
+`)
+
+		if err := r.Reload(); err == nil {
+			t.Fatal("reloaded an invalid file")
+		}
+		if cfg.Log.Level != "debug" || calls != 1 {
+			t.Fatalf("failed reload changed the config: %+v", cfg)
+		}
+	})
+}
+
+func TestReloaderRun(t *testing.T) {
+	level := "info"
+	load := func(cfg any) error {
+		cfg.(*reloadConfig).Log.Level = level
+		return nil
+	}
+
+	var cfg reloadConfig
+	cfg.Log.Level = level
+
+	r := config.NewReloader(zap.NewNop().Sugar(), &cfg, reloadConfig{}, load)
+
+	changed := make(chan string, 1)
+	r.Subscribe(func() { changed <- cfg.Log.Level })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reload := make(chan os.Signal, 1)
+
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx, reload, "", 0)
+		close(done)
+	}()
+
+	level = "debug"
+	reload <- syscall.SIGHUP
+
+	select {
+	case got := <-changed:
+		if got != "debug" {
+			t.Fatalf("got level %q, want %q", got, "debug")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("signal didn't reload the config")
+	}
+
+	cancel()
+	<-done
+}
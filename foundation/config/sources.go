@@ -0,0 +1,217 @@
+package config
+
+import (
+	"encoding"
+	"os"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sources records the settings provided by environment variables and flags,
+// named the way conf names them.
+type sources struct {
+	env   map[string]bool
+	flags map[string]bool
+}
+
+func newSources(prefix string, args []string) sources {
+	src := sources{
+		env:   make(map[string]bool),
+		flags: make(map[string]bool),
+	}
+
+	ns := strings.ToUpper(prefix) + "_"
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if key, ok := strings.CutPrefix(name, ns); ok {
+			src.env[strings.ToUpper(key)] = true
+		}
+	}
+
+	// The same as conf, the flags end at the first argument that isn't one.
+	for len(args) > 0 {
+		arg := args[0]
+		if len(arg) < 2 || arg[0] != '-' || arg == "--" {
+			break
+		}
+		args = args[1:]
+
+		name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		name, _, hasValue := strings.Cut(name, "=")
+		if !hasValue && len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+			args = args[1:]
+		}
+
+		src.flags[strings.ToLower(name)] = true
+	}
+
+	return src
+}
+
+// provided reports whether an environment variable or flag sets the field
+// with the specified key and conf tag.
+func (s sources) provided(key []string, tag string) bool {
+	envKey, flagKey := key, key
+	var short string
+
+	for _, opt := range strings.Split(tag, ",") {
+		name, val, _ := strings.Cut(opt, ":")
+		val = strings.TrimSpace(val)
+
+		switch name {
+		case "env":
+			envKey = strings.Split(val, "_")
+		case "flag":
+			flagKey = strings.Split(val, "-")
+		case "short":
+			short = val
+		}
+	}
+
+	if s.env[strings.ToUpper(strings.Join(envKey, "_"))] {
+		return true
+	}
+
+	if short != "" && s.flags[strings.ToLower(short)] {
+		return true
+	}
+
+	return s.flags[strings.ToLower(strings.Join(flagKey, "-"))]
+}
+
+// restore decodes the values of the config file into the fields of v that
+// are not provided by an environment variable or flag.
+func restore(n *yaml.Node, v reflect.Value, key []string, src sources) error {
+	if n.Kind == yaml.DocumentNode {
+		if len(n.Content) == 0 {
+			return nil
+		}
+		n = n.Content[0]
+	}
+
+	if n.Kind != yaml.MappingNode || v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		name, val := n.Content[i].Value, n.Content[i+1]
+
+		sf, ok := fieldByKey(v.Type(), name)
+		if !ok {
+			continue
+		}
+
+		tag := sf.Tag.Get("conf")
+		if tag == "-" {
+			continue
+		}
+
+		fld := v.FieldByIndex(sf.Index)
+		for fld.Kind() == reflect.Pointer && !fld.IsNil() {
+			fld = fld.Elem()
+		}
+
+		fldKey := append(append([]string(nil), key...), camelSplit(sf.Name)...)
+		if sf.Anonymous {
+			fldKey = key
+		}
+
+		if fld.Kind() == reflect.Struct && !decodesItself(fld) {
+			if err := restore(val, fld, fldKey, src); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if src.provided(fldKey, tag) {
+			continue
+		}
+
+		if err := val.Decode(fld.Addr().Interface()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fieldByKey finds the exported field a normalized key of the config file
+// sets, the same way yaml matches it.
+func fieldByKey(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.IsExported() && strings.ToLower(sf.Name) == key {
+			return sf, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// decodesItself reports whether conf treats the struct as a single value
+// instead of a group of settings.
+func decodesItself(v reflect.Value) bool {
+	switch v.Addr().Interface().(type) {
+	case encoding.TextUnmarshaler, encoding.BinaryUnmarshaler, interface{ Set(string) error }:
+		return true
+	}
+	return false
+}
+
+// camelSplit splits a field name into the words conf uses for its
+// environment variable and flag names, so FOOBar becomes FOO and Bar.
+func camelSplit(src string) []string {
+	runes := []rune(src)
+	if len(runes) < 2 {
+		return []string{src}
+	}
+
+	var out []string
+	last, lastIdx := charClass(runes[0]), 0
+
+	for i, r := range runes {
+		class := charClass(r)
+
+		if class != last {
+			switch {
+			case last == classUpper && class != classNumber:
+				if i-lastIdx > 1 {
+					out = append(out, string(runes[lastIdx:i-1]))
+					lastIdx = i - 1
+				}
+			default:
+				out = append(out, string(runes[lastIdx:i]))
+				lastIdx = i
+			}
+		}
+
+		if i == len(runes)-1 {
+			out = append(out, string(runes[lastIdx:]))
+		}
+		last = class
+	}
+
+	return out
+}
+
+const (
+	classLower = iota
+	classUpper
+	classNumber
+	classOther
+)
+
+func charClass(r rune) int {
+	switch {
+	case unicode.IsLower(r):
+		return classLower
+	case unicode.IsUpper(r):
+		return classUpper
+	case unicode.IsDigit(r):
+		return classNumber
+	}
+	return classOther
+}
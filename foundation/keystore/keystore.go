@@ -0,0 +1,97 @@
+// Package keystore implements the auth.KeyLookup interface for a set of
+// private keys held in memory or stored as PEM files.
+package keystore
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// KeyStore represents an in memory store implementation of the
+// KeyLookup interface for use with the auth package.
+type KeyStore struct {
+	store map[string]*rsa.PrivateKey
+}
+
+// NewMap constructs a KeyStore with an initial set of keys keyed by their
+// key id.
+func NewMap(store map[string]*rsa.PrivateKey) *KeyStore {
+	return &KeyStore{
+		store: store,
+	}
+}
+
+// NewFS constructs a KeyStore from the PEM files found in the root of the
+// file system. The name of a file without its .pem extension is the id of
+// the key it holds.
+func NewFS(fsys fs.FS) (*KeyStore, error) {
+	names, err := fs.Glob(fsys, "*.pem")
+	if err != nil {
+		return nil, err
+	}
+
+	ks := KeyStore{
+		store: make(map[string]*rsa.PrivateKey, len(names)),
+	}
+
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("reading key %s: %w", name, err)
+		}
+
+		key, err := ParsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing key %s: %w", name, err)
+		}
+
+		ks.store[strings.TrimSuffix(path.Base(name), ".pem")] = key
+	}
+
+	return &ks, nil
+}
+
+// PrivateKey searches the key store for a given kid and returns the
+// private key.
+func (ks *KeyStore) PrivateKey(kid string) (*rsa.PrivateKey, error) {
+	key, ok := ks.store[kid]
+	if !ok {
+		return nil, fmt.Errorf("kid %q lookup failed", kid)
+	}
+
+	return key, nil
+}
+
+// ParsePrivateKey decodes an RSA private key from a PEM block in either the
+// PKCS #1 or the PKCS #8 form.
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("not an RSA private key")
+		}
+		return rsaKey, nil
+	}
+
+	return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+}
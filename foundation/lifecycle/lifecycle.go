@@ -0,0 +1,239 @@
+// Package lifecycle manages the startup and graceful shutdown of the long
+// running parts of a service such as servers, workers and connection pools.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/maxkulish/service-api/foundation/listener"
+	"go.uber.org/zap"
+)
+
+// component represents a part of the service managed by the Lifecycle.
+//
+//	open    acquires resources that must be ready before anything runs, like
+//	        binding a port. Optional.
+//	release undoes open when the component never got to run. Optional.
+//	run     blocks for as long as the component is working. Optional.
+//	stop    asks a running component to finish within the deadline of ctx.
+type component struct {
+	name    string
+	open    func() error
+	release func() error
+	run     func() error
+	stop    func(ctx context.Context) error
+}
+
+// Lifecycle starts a set of components and stops them in the reverse order
+// they were added once the service is asked to shut down.
+type Lifecycle struct {
+	log        *zap.SugaredLogger
+	components []component
+	started    chan struct{}
+
+	mu        sync.Mutex
+	listeners map[string]net.Listener
+}
+
+// New constructs a Lifecycle for managing a service's components.
+func New(log *zap.SugaredLogger) *Lifecycle {
+	return &Lifecycle{
+		log:       log,
+		started:   make(chan struct{}),
+		listeners: make(map[string]net.Listener),
+	}
+}
+
+// Started returns a channel that is closed once every component is running.
+func (l *Lifecycle) Started() <-chan struct{} {
+	return l.started
+}
+
+// Listeners returns the listeners of the servers by name, so they can be
+// handed over to a new process.
+func (l *Lifecycle) Listeners() map[string]net.Listener {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	listeners := make(map[string]net.Listener, len(l.listeners))
+	for name, ln := range l.listeners {
+		listeners[name] = ln
+	}
+
+	return listeners
+}
+
+// AddServer registers an http server. The address of the server is bound
+// before any component starts running so a port that is already in use fails
+// the startup. See listener.Listen for the supported addresses. A socket
+// inherited under the name of the server is used instead of the address.
+// The server uses TLS when its TLSConfig is set. A server that can't drain
+// its connections in time is closed.
+func (l *Lifecycle) AddServer(name string, srv *http.Server) {
+	var ln net.Listener
+
+	l.components = append(l.components, component{
+		name: name,
+		open: func() error {
+			var err error
+			ln, err = l.listen(name, srv.Addr)
+			if err != nil {
+				return err
+			}
+
+			l.mu.Lock()
+			l.listeners[name] = ln
+			l.mu.Unlock()
+
+			return nil
+		},
+		release: func() error {
+			return ln.Close()
+		},
+		run: func() error {
+			l.log.Infow("startup", "status", name+" router started", "host", ln.Addr().String(), "tls", srv.TLSConfig != nil)
+
+			if srv.TLSConfig != nil {
+				return srv.ServeTLS(ln, "", "")
+			}
+			return srv.Serve(ln)
+		},
+		stop: func(ctx context.Context) error {
+			if err := srv.Shutdown(ctx); err != nil {
+				srv.Close()
+				return err
+			}
+			return nil
+		},
+	})
+}
+
+// listen prefers a socket inherited under the name of the server, handed
+// over by the process this one is replacing, to binding the address.
+func (l *Lifecycle) listen(name string, addr string) (net.Listener, error) {
+	ln, ok, err := listener.Inherited(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		l.log.Infow("startup", "status", "using inherited socket", "component", name, "host", ln.Addr().String())
+		return ln, nil
+	}
+
+	return listener.Listen(addr)
+}
+
+// Add registers a component such as a background worker. The run function
+// must block until the stop function is called.
+func (l *Lifecycle) Add(name string, run func() error, stop func(ctx context.Context) error) {
+	l.components = append(l.components, component{
+		name: name,
+		run:  run,
+		stop: stop,
+	})
+}
+
+// AddCloser registers a resource that has nothing to run but must be
+// released on shutdown, such as a database connection pool.
+func (l *Lifecycle) AddCloser(name string, stop func(ctx context.Context) error) {
+	l.components = append(l.components, component{
+		name: name,
+		stop: stop,
+	})
+}
+
+// Run starts all the components and blocks until a signal is received on the
+// shutdown channel or a component fails. The components are then stopped in
+// the reverse order they were added, sharing the provided timeout. Every
+// component is stopped or released by the time Run returns.
+func (l *Lifecycle) Run(shutdown <-chan os.Signal, timeout time.Duration) error {
+	opened := make([]bool, len(l.components))
+	running := make([]bool, len(l.components))
+
+	for i, c := range l.components {
+		if c.open == nil {
+			continue
+		}
+
+		if err := c.open(); err != nil {
+			err = fmt.Errorf("starting %s: %w", c.name, err)
+			if serr := l.shutdown(opened, running, timeout); serr != nil {
+				return errors.Join(err, serr)
+			}
+			return err
+		}
+		opened[i] = true
+	}
+
+	errs := make(chan error, len(l.components))
+
+	for i, c := range l.components {
+		if c.run == nil {
+			continue
+		}
+
+		running[i] = true
+		go func(c component) {
+			err := c.run()
+			if err == nil {
+				err = errors.New("stopped unexpectedly")
+			}
+			errs <- fmt.Errorf("%s: %w", c.name, err)
+		}(c)
+	}
+
+	close(l.started)
+
+	select {
+	case err := <-errs:
+		l.log.Errorw("shutdown", "status", "component failed", "ERROR", err)
+
+		if serr := l.shutdown(opened, running, timeout); serr != nil {
+			return errors.Join(err, serr)
+		}
+		return err
+
+	case sig := <-shutdown:
+		l.log.Infow("shutdown", "status", "shutdown started", "signal", sig)
+		defer l.log.Infow("shutdown", "status", "shutdown complete", "signal", sig)
+
+		return l.shutdown(opened, running, timeout)
+	}
+}
+
+// shutdown stops the running components and releases the ones that were
+// opened but never ran, in the reverse order they were added.
+func (l *Lifecycle) shutdown(opened []bool, running []bool, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+
+	for i := len(l.components) - 1; i >= 0; i-- {
+		c := l.components[i]
+
+		var err error
+		switch {
+		case running[i] || (c.run == nil && c.stop != nil):
+			l.log.Infow("shutdown", "status", "stopping", "component", c.name)
+			err = c.stop(ctx)
+
+		case opened[i] && c.release != nil:
+			err = c.release()
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not stop %s gracefully: %w", c.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,205 @@
+package lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/maxkulish/service-api/foundation/lifecycle"
+	"go.uber.org/zap"
+)
+
+// recorder collects, in the order the components stop, the servers that
+// still accept connections at that moment.
+type recorder struct {
+	lc    *lifecycle.Lifecycle
+	mu    sync.Mutex
+	stops []string
+}
+
+func (r *recorder) stop(name string) {
+	var serving []string
+	for _, srv := range []string{"debug", "api"} {
+		ln, ok := r.lc.Listeners()[srv]
+		if !ok {
+			continue
+		}
+
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			continue
+		}
+		conn.Close()
+		serving = append(serving, srv)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stops = append(r.stops, fmt.Sprintf("%s%v", name, serving))
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.stops...)
+}
+
+func newServer(addr string) *http.Server {
+	return &http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	}
+}
+
+func runAsync(lc *lifecycle.Lifecycle, shutdown chan os.Signal) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- lc.Run(shutdown, 5*time.Second)
+	}()
+	return done
+}
+
+func wait(t *testing.T, done <-chan error) error {
+	t.Helper()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not return")
+		return nil
+	}
+}
+
+func TestRunStopsInReverseOrder(t *testing.T) {
+	lc := lifecycle.New(zap.NewNop().Sugar())
+	rec := recorder{lc: lc}
+
+	closer := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			rec.stop(name)
+			return nil
+		}
+	}
+
+	lc.AddCloser("database", closer("database"))
+	lc.AddServer("debug", newServer("127.0.0.1:0"))
+	lc.AddCloser("cache", closer("cache"))
+	lc.AddServer("api", newServer("127.0.0.1:0"))
+
+	stop := make(chan struct{})
+	lc.Add("worker",
+		func() error {
+			<-stop
+			return nil
+		},
+		func(ctx context.Context) error {
+			rec.stop("worker")
+			close(stop)
+			return nil
+		},
+	)
+
+	shutdown := make(chan os.Signal, 1)
+	done := runAsync(lc, shutdown)
+
+	select {
+	case <-lc.Started():
+	case err := <-done:
+		t.Fatalf("Run returned before starting: %v", err)
+	}
+
+	ln, ok := lc.Listeners()["api"]
+	if !ok {
+		t.Fatal("api listener not registered")
+	}
+
+	resp, err := http.Get("http://" + ln.Addr().String())
+	if err != nil {
+		t.Fatalf("api not serving: %v", err)
+	}
+	resp.Body.Close()
+
+	shutdown <- syscall.SIGTERM
+
+	if err := wait(t, done); err != nil {
+		t.Fatalf("Run returned an error: %v", err)
+	}
+
+	// Each component sees the ones added before it still running and the
+	// ones added after it already stopped.
+	want := []string{"worker[debug api]", "cache[debug]", "database[]"}
+	got := rec.get()
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("stop order: got %v, want %v", got, want)
+	}
+}
+
+func TestRunReleasesOpenedOnFailure(t *testing.T) {
+	// Holding the port makes the second server fail to bind.
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	lc := lifecycle.New(zap.NewNop().Sugar())
+	lc.AddServer("debug", newServer("127.0.0.1:0"))
+	lc.AddServer("api", newServer(busy.Addr().String()))
+
+	ran := make(chan struct{}, 1)
+	lc.Add("worker",
+		func() error {
+			ran <- struct{}{}
+			return nil
+		},
+		func(context.Context) error {
+			return nil
+		},
+	)
+
+	err = wait(t, runAsync(lc, make(chan os.Signal, 1)))
+	if err == nil {
+		t.Fatal("Run succeeded with a port in use")
+	}
+
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		t.Fatalf("expected a listen error, got: %v", err)
+	}
+
+	select {
+	case <-ran:
+		t.Fatal("worker ran after a failed startup")
+	default:
+	}
+
+	select {
+	case <-lc.Started():
+		t.Fatal("started closed after a failed startup")
+	default:
+	}
+
+	// The debug server bound its port before the api failed. Binding the
+	// same address again only works if it was released.
+	ln, ok := lc.Listeners()["debug"]
+	if !ok {
+		t.Fatal("debug listener not registered")
+	}
+
+	again, err := net.Listen("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("debug listener not released: %v", err)
+	}
+	again.Close()
+}
@@ -0,0 +1,200 @@
+// Package listener provides support for creating network listeners from an
+// address that names the kind of socket to use.
+package listener
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Listen returns a listener for the address. The supported forms are:
+//
+//	host:port, tcp://host:port  a TCP socket.
+//	unix:///run/sales.sock      a Unix domain socket. A stale socket file left
+//	                            behind by a previous process is removed.
+//	fd://3                      a socket inherited by the process as an open
+//	                            file descriptor.
+//	systemd://name              a socket passed by systemd socket activation,
+//	                            matched by its FileDescriptorName or by its
+//	                            position when name is a number.
+func Listen(addr string) (net.Listener, error) {
+	scheme, rest, found := strings.Cut(addr, "://")
+	if !found {
+		return net.Listen("tcp", addr)
+	}
+
+	switch scheme {
+	case "tcp":
+		return net.Listen("tcp", rest)
+
+	case "unix":
+		return listenUnix(rest)
+
+	case "fd":
+		fd, err := strconv.Atoi(rest)
+		if err != nil || fd < 3 {
+			return nil, fmt.Errorf("invalid file descriptor %q", rest)
+		}
+		return fileListener(uintptr(fd), addr)
+
+	case "systemd":
+		return activated(rest)
+	}
+
+	return nil, fmt.Errorf("unsupported address scheme %q", scheme)
+}
+
+func listenUnix(path string) (net.Listener, error) {
+	if path == "" {
+		return nil, errors.New("missing unix socket path")
+	}
+
+	// Only remove the file if it is a socket nobody is listening on, so a
+	// running process never loses its socket and no other file is deleted.
+	if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("unix socket %s is in use", path)
+		}
+
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("removing stale unix socket: %w", err)
+		}
+	}
+
+	return net.Listen("unix", path)
+}
+
+// fileListener turns an inherited file descriptor into a listener. The
+// listener holds its own copy of the descriptor so the original is closed.
+func fileListener(fd uintptr, name string) (net.Listener, error) {
+	f := os.NewFile(fd, name)
+	if f == nil {
+		return nil, fmt.Errorf("invalid file descriptor %d", fd)
+	}
+	defer f.Close()
+
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("listening on file descriptor %d: %w", fd, err)
+	}
+
+	return ln, nil
+}
+
+// =============================================================================
+
+// The first file descriptor passed by systemd, following stdin, stdout and
+// stderr.
+const listenFDsStart = 3
+
+var (
+	activation     sync.Once
+	activationMu   sync.Mutex
+	activationFDs  []uintptr
+	activationName []string
+	activationErr  error
+)
+
+// Inherited returns the socket passed to the process under the name, either
+// by systemd socket activation or by a parent process handing over its
+// sockets during an upgrade. It reports false when there is no such socket.
+func Inherited(name string) (net.Listener, bool, error) {
+	fd, ok := take(name, false)
+	if !ok {
+		return nil, false, nil
+	}
+
+	ln, err := fileListener(fd, "systemd://"+name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return ln, true, nil
+}
+
+// activated returns the listener systemd passed for the name.
+func activated(name string) (net.Listener, error) {
+	activation.Do(readActivation)
+	if activationErr != nil {
+		return nil, activationErr
+	}
+
+	fd, ok := take(name, true)
+	if !ok {
+		return nil, fmt.Errorf("no socket activated for %q", name)
+	}
+
+	return fileListener(fd, "systemd://"+name)
+}
+
+// take returns the descriptor passed for the name, matched by its name or
+// by its position when byIndex is true. Every descriptor can only be taken
+// once.
+func take(name string, byIndex bool) (uintptr, bool) {
+	activation.Do(readActivation)
+
+	activationMu.Lock()
+	defer activationMu.Unlock()
+
+	idx := -1
+	for i, n := range activationName {
+		if n == name && i < len(activationFDs) {
+			idx = i
+			break
+		}
+	}
+
+	if idx == -1 && byIndex {
+		if i, err := strconv.Atoi(name); err == nil && i >= 0 && i < len(activationFDs) {
+			idx = i
+		}
+	}
+
+	if idx == -1 || activationFDs[idx] == 0 {
+		return 0, false
+	}
+
+	fd := activationFDs[idx]
+	activationFDs[idx] = 0
+
+	return fd, true
+}
+
+// readActivation reads the LISTEN_PID, LISTEN_FDS and LISTEN_FDNAMES
+// variables set by systemd. The variables are removed so they are not
+// mistaken for our own by a child process. LISTEN_PID is optional since a
+// parent process handing over its sockets can't know the pid of the child
+// before it starts.
+func readActivation() {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	if pid := os.Getenv("LISTEN_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		activationErr = errors.New("sockets were activated for another process")
+		return
+	}
+
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		activationErr = errors.New("no sockets were activated")
+		return
+	}
+
+	activationFDs = make([]uintptr, n)
+	for i := range activationFDs {
+		activationFDs[i] = uintptr(listenFDsStart + i)
+	}
+
+	if names := os.Getenv("LISTEN_FDNAMES"); names != "" {
+		activationName = strings.Split(names, ":")
+	}
+}
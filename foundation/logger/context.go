@@ -0,0 +1,17 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/maxkulish/service-api/foundation/web"
+	"go.uber.org/zap"
+)
+
+// WithTrace returns a child logger that adds the trace id of the request
+// being handled by ctx to every entry it writes. Code running outside of a
+// request gets the zero trace id, which keeps the trace_id column consistent.
+//
+//	logger.WithTrace(ctx, log).Infow("user created", "user_id", usr.ID)
+func WithTrace(ctx context.Context, log *zap.SugaredLogger) *zap.SugaredLogger {
+	return log.With("trace_id", web.GetTraceID(ctx))
+}
@@ -8,10 +8,12 @@ import (
 )
 
 // New constructs a Sugared Logger that writes to stdout and
-// provides human-readable timestamps.
-func New(service string, outputPaths ...string) (*zap.SugaredLogger, error) {
+// provides human-readable timestamps. The level can be changed while the
+// logger is in use.
+func New(service string, level zap.AtomicLevel, outputPaths ...string) (*zap.SugaredLogger, error) {
 	config := zap.NewProductionConfig()
 
+	config.Level = level
 	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 	config.DisableStacktrace = true
 	config.InitialFields = map[string]any{
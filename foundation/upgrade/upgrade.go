@@ -0,0 +1,169 @@
+// Package upgrade provides support for replacing a running binary with a new
+// one without closing its listening sockets. The new process inherits the
+// sockets the same way it would from systemd socket activation, so it keeps
+// accepting connections on them while the old process drains.
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// envReadyFD holds the file descriptor the new process uses to report it is
+// ready to serve.
+const envReadyFD = "UPGRADE_READY_FD"
+
+// filer is implemented by the listeners that can hand over their socket.
+type filer interface {
+	File() (*os.File, error)
+}
+
+// Start starts the new binary, found at the path of the running executable,
+// and passes it the listeners by name. It waits for the new process to call
+// Ready before returning. If that doesn't happen before the context is done,
+// the new process is killed and the listeners stay with this process.
+func Start(ctx context.Context, listeners map[string]net.Listener) (*os.Process, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("locating executable: %w", err)
+	}
+
+	names := make([]string, 0, len(listeners))
+	for name := range listeners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	files := []*os.File{os.Stdin, os.Stdout, os.Stderr}
+	defer func() {
+		for _, f := range files[3:] {
+			f.Close()
+		}
+	}()
+
+	for _, name := range names {
+		ln, ok := listeners[name].(filer)
+		if !ok {
+			return nil, fmt.Errorf("listener %s can't be handed over", name)
+		}
+
+		f, err := ln.File()
+		if err != nil {
+			return nil, fmt.Errorf("handing over listener %s: %w", name, err)
+		}
+		files = append(files, f)
+	}
+
+	ready, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("creating ready pipe: %w", err)
+	}
+	defer ready.Close()
+	files = append(files, w)
+
+	env := []string{
+		"LISTEN_FDS=" + strconv.Itoa(len(names)),
+		"LISTEN_FDNAMES=" + strings.Join(names, ":"),
+		envReadyFD + "=" + strconv.Itoa(len(files)-1),
+	}
+	for _, kv := range os.Environ() {
+		switch strings.SplitN(kv, "=", 2)[0] {
+		case "LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES", envReadyFD:
+			continue
+		}
+		env = append(env, kv)
+	}
+
+	p, err := os.StartProcess(exe, os.Args, &os.ProcAttr{
+		Env:   env,
+		Files: files,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("starting %s: %w", exe, err)
+	}
+
+	// Close our copy of the write end so the read fails if the new process
+	// exits without reporting it is ready.
+	w.Close()
+
+	result := make(chan error, 1)
+	go func() {
+		data, err := io.ReadAll(ready)
+		switch {
+		case err != nil:
+			result <- err
+		case string(data) != "ready":
+			result <- errors.New("new process exited before it was ready")
+		default:
+			result <- nil
+		}
+	}()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			return nil, stop(p, err)
+		}
+
+	case <-ctx.Done():
+		return nil, stop(p, fmt.Errorf("waiting for new process: %w", ctx.Err()))
+	}
+
+	// The new process owns the unix sockets now, closing the listeners on
+	// shutdown must not remove the socket files from under it.
+	for _, ln := range listeners {
+		if ul, ok := ln.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+
+	return p, nil
+}
+
+// stop kills a new process that failed to get ready and waits for it, so it
+// doesn't linger as a zombie. How it exited is added to the error.
+func stop(p *os.Process, err error) error {
+	p.Kill()
+
+	state, werr := p.Wait()
+	if werr != nil {
+		return fmt.Errorf("%w: waiting for new process: %v", err, werr)
+	}
+
+	return fmt.Errorf("%w: new process %s", err, state)
+}
+
+// Ready reports to the process that started this one as part of an upgrade
+// that it is serving, so the old process can shut down. It does nothing if
+// this process was not started by an upgrade.
+func Ready() error {
+	v := os.Getenv(envReadyFD)
+	if v == "" {
+		return nil
+	}
+	os.Unsetenv(envReadyFD)
+
+	fd, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q", envReadyFD, v)
+	}
+
+	f := os.NewFile(uintptr(fd), "upgrade-ready")
+	if f == nil {
+		return fmt.Errorf("invalid %s %q", envReadyFD, v)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString("ready"); err != nil {
+		return fmt.Errorf("reporting ready: %w", err)
+	}
+
+	return nil
+}
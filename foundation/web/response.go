@@ -0,0 +1,33 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// Respond converts a Go value to JSON and sends it to the client.
+// The status code is only recorded once the value could be converted, so
+// an error leaves the response to be written by the error handling.
+func Respond(ctx context.Context, w http.ResponseWriter, data any, statusCode int) error {
+	if statusCode == http.StatusNoContent {
+		SetStatusCode(ctx, statusCode)
+		w.WriteHeader(statusCode)
+		return nil
+	}
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	SetStatusCode(ctx, statusCode)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if _, err := w.Write(jsonData); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+// Package web contains a small web framework extension.
+package web
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/dimfeld/httptreemux/v5"
+	"github.com/google/uuid"
+)
+
+// A Handler is a type that handles a http request within our own little mini
+// framework.
+type Handler func(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+
+// App is the entrypoint into our application and what configures our context
+// object for each of our http handlers. Feel free to add any configuration
+// data/logic on this App struct.
+type App struct {
+	*httptreemux.ContextMux
+	shutdown     chan os.Signal
+	mw           []Middleware
+	readTimeout  atomic.Int64
+	writeTimeout atomic.Int64
+}
+
+// NewApp creates an App value that handle a set of routes for the application.
+// The middleware provided here is applied to every route.
+func NewApp(shutdown chan os.Signal, mw ...Middleware) *App {
+	return &App{
+		ContextMux: httptreemux.NewContextMux(),
+		shutdown:   shutdown,
+		mw:         mw,
+	}
+}
+
+// SignalShutdown is used to gracefully shut down the app when an integrity
+// issue is identified. A shutdown already in progress is not signalled twice.
+func (a *App) SignalShutdown() {
+	select {
+	case a.shutdown <- syscall.SIGTERM:
+	default:
+	}
+}
+
+// SetTimeouts changes the read and write deadlines applied to every request
+// from the moment its handler is called. It is safe to call while the App is
+// serving requests, unlike changing the timeouts of the http.Server. A zero
+// duration leaves the deadline set by the http.Server in place.
+func (a *App) SetTimeouts(read time.Duration, write time.Duration) {
+	a.readTimeout.Store(int64(read))
+	a.writeTimeout.Store(int64(write))
+}
+
+// Group creates a set of routes sharing the same path prefix and middleware.
+func (a *App) Group(group string, mw ...Middleware) *Group {
+	return &Group{
+		app:   a,
+		group: group,
+		mw:    mw,
+	}
+}
+
+// Handle sets a handler function for a given HTTP method and path pair
+// to the application server mux. Requests run through the application
+// middleware first and the route middleware last, in the order provided.
+func (a *App) Handle(method string, group string, path string, handler Handler, mw ...Middleware) {
+	handler = wrapMiddleware(mw, handler)
+	handler = wrapMiddleware(a.mw, handler)
+
+	h := func(w http.ResponseWriter, r *http.Request) {
+		a.setDeadlines(w)
+
+		v := Values{
+			TraceID: uuid.NewString(),
+			Now:     time.Now().UTC(),
+		}
+		ctx := context.WithValue(r.Context(), key, &v)
+		w = &responseWriter{ResponseWriter: w, v: &v}
+
+		if err := handler(ctx, w, r); err != nil {
+			a.respondError(ctx, w, err)
+
+			if IsShutdown(err) {
+				a.SignalShutdown()
+			}
+		}
+	}
+
+	finalPath := path
+	if group != "" {
+		finalPath = "/" + group + path
+	}
+
+	a.ContextMux.Handle(method, finalPath, h)
+}
+
+// setDeadlines applies the timeouts provided to SetTimeouts. A writer that
+// doesn't support deadlines keeps the ones set by the http.Server.
+func (a *App) setDeadlines(w http.ResponseWriter) {
+	var rc *http.ResponseController
+
+	if d := time.Duration(a.readTimeout.Load()); d > 0 {
+		rc = http.NewResponseController(w)
+		rc.SetReadDeadline(time.Now().Add(d))
+	}
+
+	if d := time.Duration(a.writeTimeout.Load()); d > 0 {
+		if rc == nil {
+			rc = http.NewResponseController(w)
+		}
+		rc.SetWriteDeadline(time.Now().Add(d))
+	}
+}
+
+// respondError is the single place every error returned by a handler ends up.
+// The details of the error are never sent to the client since there is no way
+// to know if they are safe to expose.
+func (a *App) respondError(ctx context.Context, w http.ResponseWriter, err error) {
+	// If the handler already wrote a response there is nothing left to do.
+	if GetValues(ctx).StatusCode != 0 {
+		return
+	}
+
+	SetStatusCode(ctx, http.StatusInternalServerError)
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// =============================================================================
+
+// Group represents a set of routes registered under a common path prefix
+// that share middleware.
+type Group struct {
+	app   *App
+	group string
+	mw    []Middleware
+}
+
+// Handle sets a handler function for a given HTTP method and path pair
+// inside the group. Requests run through the application middleware, then
+// the group middleware and finally the route middleware.
+func (g *Group) Handle(method string, path string, handler Handler, mw ...Middleware) {
+	handler = wrapMiddleware(mw, handler)
+	handler = wrapMiddleware(g.mw, handler)
+
+	g.app.Handle(method, g.group, path, handler)
+}
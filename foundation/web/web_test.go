@@ -0,0 +1,332 @@
+package web_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/maxkulish/service-api/foundation/web"
+)
+
+// trace returns a middleware that appends its name to calls before and
+// after the handler it wraps runs.
+func trace(calls *[]string, name string) web.Middleware {
+	return func(handler web.Handler) web.Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			*calls = append(*calls, name)
+			err := handler(ctx, w, r)
+			*calls = append(*calls, "/"+name)
+			return err
+		}
+	}
+}
+
+func serve(h http.Handler, method string, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+
+	app := web.NewApp(make(chan os.Signal, 1), trace(&calls, "app1"), nil, trace(&calls, "app2"))
+	g := app.Group("v1", nil, trace(&calls, "group"))
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		calls = append(calls, "handler")
+		return web.Respond(ctx, w, nil, http.StatusNoContent)
+	}
+	g.Handle(http.MethodGet, "/users", handler, trace(&calls, "route1"), nil, trace(&calls, "route2"))
+
+	if w := serve(app, http.MethodGet, "/v1/users"); w.Code != http.StatusNoContent {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusNoContent)
+	}
+
+	want := []string{"app1", "app2", "group", "route1", "route2", "handler", "/route2", "/route1", "/group", "/app2", "/app1"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("got calls\n%v\nwant\n%v", calls, want)
+	}
+}
+
+func TestMiddlewareRoute(t *testing.T) {
+	var calls []string
+
+	app := web.NewApp(make(chan os.Signal, 1), trace(&calls, "app"))
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		calls = append(calls, "handler")
+		return web.Respond(ctx, w, nil, http.StatusNoContent)
+	}
+	app.Handle(http.MethodGet, "", "/test", handler, trace(&calls, "route"))
+
+	serve(app, http.MethodGet, "/test")
+
+	want := []string{"app", "route", "handler", "/route", "/app"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("got calls %v, want %v", calls, want)
+	}
+}
+
+func TestGroupMiddlewareIsolated(t *testing.T) {
+	var calls []string
+
+	app := web.NewApp(make(chan os.Signal, 1))
+	v1 := app.Group("v1", trace(&calls, "v1"))
+	v2 := app.Group("v2", trace(&calls, "v2"))
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, nil, http.StatusNoContent)
+	}
+	v1.Handle(http.MethodGet, "/users", handler)
+	v2.Handle(http.MethodGet, "/users", handler)
+
+	serve(app, http.MethodGet, "/v2/users")
+
+	want := []string{"v2", "/v2"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("got calls %v, want %v", calls, want)
+	}
+}
+
+func TestGroupPrefix(t *testing.T) {
+	app := web.NewApp(make(chan os.Signal, 1))
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, r.URL.Path+" "+web.Param(r, "id"), http.StatusOK)
+	}
+	app.Handle(http.MethodGet, "", "/test", handler)
+	app.Handle(http.MethodGet, "v0", "/test", handler)
+	app.Group("v1").Handle(http.MethodGet, "/users/:id", handler)
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{path: "/test", status: http.StatusOK, body: `"/test "`},
+		{path: "/v0/test", status: http.StatusOK, body: `"/v0/test "`},
+		{path: "/v1/users/42", status: http.StatusOK, body: `"/v1/users/42 42"`},
+		{path: "/users/42", status: http.StatusNotFound},
+		{path: "/v1users/42", status: http.StatusNotFound},
+		{path: "/v1/v1/users/42", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := serve(app, http.MethodGet, tt.path)
+			if w.Code != tt.status {
+				t.Fatalf("got status %d, want %d", w.Code, tt.status)
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Fatalf("got body %s, want %s", w.Body, tt.body)
+			}
+		})
+	}
+}
+
+func TestRespondError(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler web.Handler
+		status  int
+		body    string
+	}{
+		{
+			name: "error",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return errors.New("database password is hunter2")
+			},
+			status: http.StatusInternalServerError,
+			body:   "Internal Server Error\n",
+		},
+		{
+			name: "unencodable response",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return web.Respond(ctx, w, func() {}, http.StatusOK)
+			},
+			status: http.StatusInternalServerError,
+			body:   "Internal Server Error\n",
+		},
+		{
+			name: "error after responding",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				if err := web.Respond(ctx, w, "created", http.StatusCreated); err != nil {
+					return err
+				}
+				return errors.New("failed after responding")
+			},
+			status: http.StatusCreated,
+			body:   `"created"`,
+		},
+		{
+			name: "error after writing",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				w.Write([]byte("partial"))
+				return errors.New("failed while writing")
+			},
+			status: http.StatusOK,
+			body:   "partial",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := web.NewApp(make(chan os.Signal, 1))
+			app.Handle(http.MethodGet, "", "/test", tt.handler)
+
+			w := serve(app, http.MethodGet, "/test")
+			if w.Code != tt.status {
+				t.Fatalf("got status %d, want %d", w.Code, tt.status)
+			}
+			if w.Body.String() != tt.body {
+				t.Fatalf("got body %q, want %q", w.Body, tt.body)
+			}
+		})
+	}
+}
+
+func TestResponseWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler web.Handler
+		status  int
+		bytes   int
+	}{
+		{
+			name: "respond",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return web.Respond(ctx, w, "hello", http.StatusAccepted)
+			},
+			status: http.StatusAccepted,
+			bytes:  len(`"hello"`),
+		},
+		{
+			name: "no content",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return web.Respond(ctx, w, nil, http.StatusNoContent)
+			},
+			status: http.StatusNoContent,
+		},
+		{
+			name: "write header",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				w.WriteHeader(http.StatusTeapot)
+				w.Write([]byte("short"))
+				w.Write([]byte(" and stout"))
+				return nil
+			},
+			status: http.StatusTeapot,
+			bytes:  len("short and stout"),
+		},
+		{
+			name: "implicit ok",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				w.Write([]byte("ok"))
+				return nil
+			},
+			status: http.StatusOK,
+			bytes:  len("ok"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v web.Values
+
+			// The route middleware reads the values once the handler is done.
+			record := func(handler web.Handler) web.Handler {
+				return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					err := handler(ctx, w, r)
+					v = *web.GetValues(ctx)
+					return err
+				}
+			}
+
+			app := web.NewApp(make(chan os.Signal, 1))
+			app.Handle(http.MethodGet, "", "/test", tt.handler, record)
+
+			w := serve(app, http.MethodGet, "/test")
+			if w.Code != tt.status {
+				t.Fatalf("got status %d, want %d", w.Code, tt.status)
+			}
+
+			if v.StatusCode != tt.status || v.BytesWritten != tt.bytes {
+				t.Fatalf("got status %d with %d bytes recorded, want %d with %d", v.StatusCode, v.BytesWritten, tt.status, tt.bytes)
+			}
+			if v.TraceID == "" || v.Now.IsZero() {
+				t.Fatalf("got values without a trace id or time: %+v", v)
+			}
+		})
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	shutdown := make(chan os.Signal, 1)
+	app := web.NewApp(shutdown)
+
+	app.Handle(http.MethodGet, "", "/fail", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return errors.New("failed")
+	})
+	app.Handle(http.MethodGet, "", "/shutdown", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return fmt.Errorf("checking integrity: %w", web.NewShutdownError("data corrupted"))
+	})
+
+	serve(app, http.MethodGet, "/fail")
+
+	select {
+	case sig := <-shutdown:
+		t.Fatalf("got signal %v for an ordinary error", sig)
+	default:
+	}
+
+	w := serve(app, http.MethodGet, "/shutdown")
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if strings.Contains(w.Body.String(), "corrupted") {
+		t.Fatalf("got the error in the body: %s", w.Body)
+	}
+
+	// The channel is full by now, a second shutdown error must not block
+	// the request.
+	done := make(chan struct{})
+	go func() {
+		serve(app, http.MethodGet, "/shutdown")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("second shutdown error blocked the request")
+	}
+
+	select {
+	case sig := <-shutdown:
+		if sig != syscall.SIGTERM {
+			t.Fatalf("got signal %v, want %v", sig, syscall.SIGTERM)
+		}
+	default:
+		t.Fatal("shutdown error didn't signal a shutdown")
+	}
+}
+
+func TestIsShutdown(t *testing.T) {
+	err := web.NewShutdownError("data corrupted")
+
+	if !web.IsShutdown(err) || !web.IsShutdown(fmt.Errorf("wrapped: %w", err)) {
+		t.Fatal("shutdown error not recognized")
+	}
+	if web.IsShutdown(errors.New("data corrupted")) || web.IsShutdown(nil) {
+		t.Fatal("ordinary error taken for a shutdown error")
+	}
+}
@@ -0,0 +1,43 @@
+package web
+
+import "net/http"
+
+// responseWriter records the status code and the number of bytes written for
+// a request so middleware can report on them once the handler returns.
+type responseWriter struct {
+	http.ResponseWriter
+	v *Values
+}
+
+// WriteHeader records the status code before sending it to the client.
+func (rw *responseWriter) WriteHeader(statusCode int) {
+	rw.v.StatusCode = statusCode
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records the number of bytes sent to the client. A write without a
+// prior call to WriteHeader implies a 200 status code.
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if rw.v.StatusCode == 0 {
+		rw.v.StatusCode = http.StatusOK
+	}
+
+	n, err := rw.ResponseWriter.Write(b)
+	rw.v.BytesWritten += n
+
+	return n, err
+}
+
+// Flush sends any buffered data to the client if the underlying writer
+// supports it.
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the original writer so http.ResponseController can reach
+// the optional interfaces it implements.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}